@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/tao"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var alignedCorpus string
+
+var alignedCmd = &cobra.Command{
+	Use:   "aligned <chapter>",
+	Short: "Show a Tao Te Ching chapter in every loaded translation",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		chapter, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatalf("Invalid chapter %q\n", args[0])
+		}
+
+		var chapters []*tao.Chapter
+		if alignedCorpus != "" {
+			chapters, err = localAlignedChapters(alignedCorpus, chapter)
+		} else {
+			chapters, err = remoteAlignedChapters(chapter)
+		}
+		if err != nil {
+			log.Fatalf("Failed to find chapter %d: %v\n", chapter, err)
+		}
+		for _, c := range chapters {
+			fmt.Printf("%s chapter %d\n", c.Translation, c.Chapter)
+			for i, stanza := range c.Stanzas {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("\t%s\n", strings.Join(stanza.Lines, "\n\t"))
+			}
+			fmt.Println()
+		}
+	},
+}
+
+// remoteAlignedChapters finds chapter in the translations loaded into MongoDB.
+func remoteAlignedChapters(chapter int) ([]*tao.Chapter, error) {
+	ctx := context.Background()
+	clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+	mongodb, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer mongodb.Disconnect(ctx)
+	return nvoke.NewRetrievalService(mongodb, nil, nil).Aligned(ctx, chapter, translations)
+}
+
+// localAlignedChapters parses the Tao source texts at location and returns
+// chapter in each of the selected translations.
+func localAlignedChapters(location string, chapter int) ([]*tao.Chapter, error) {
+	parsed, err := (&tao.Parser{}).Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	chapters := make([]*tao.Chapter, 0)
+	for _, c := range parsed {
+		if c.Chapter != chapter {
+			continue
+		}
+		if len(translations) > 0 && !containsFold(translations, c.Translation) {
+			continue
+		}
+		chapters = append(chapters, c)
+	}
+	if len(chapters) == 0 {
+		return nil, nvoke.ErrReferenceNotFound
+	}
+	return chapters, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	alignedCmd.Flags().StringSliceVarP(&translations, "translation", "t", nil, "Translations to show, defaults to all")
+	alignedCmd.Flags().StringVar(&alignedCorpus, "corpus", "", "Read this Tao source directory instead of MongoDB, such as "+tao.DefaultLocation)
+	rootCmd.AddCommand(alignedCmd)
+}
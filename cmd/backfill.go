@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/embedding"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var concurrency int
+
+// Backfill embeds the documents in the active collection of kb whose
+// embedding is missing or does not have the knowledge base's dimensions, and
+// stores the new embeddings in place. With dryRun the documents are only
+// listed.
+func Backfill(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase, format nvoke.Format, dryRun bool) error {
+	catalog := nvoke.NewCatalog(client)
+	active, err := catalog.ActiveCollection(ctx, kb)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %v", err)
+	}
+	collection := client.Database(kb.Db).Collection(active)
+
+	generator := newKnowledgeBaseGenerator(kb)
+	// Documents are selected by the length of their embedding, so without
+	// known dimensions every document would be embedded again.
+	provenance, ok := embedding.Describe(generator)
+	if !ok || provenance.Dimensions <= 0 {
+		return errors.New("the embedding dimensions are unknown, set them with --dimensions")
+	}
+	entry, err := catalog.Get(ctx, kb)
+	if err != nil && !errors.Is(err, nvoke.ErrNotCataloged) {
+		return err
+	}
+	if entry != nil && entry.Provenance.Model != "" && !entry.Provenance.Matches(provenance) {
+		return fmt.Errorf("%s.%s was embedded with %s, not %s", kb.Db, active, entry.Provenance, provenance)
+	}
+
+	filter := bson.D{{Key: kb.Path, Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$size", Value: provenance.Dimensions}}}}}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	ids := make([]bson.RawValue, 0)
+	documents := make([]interface{}, 0)
+	for cursor.Next(ctx) {
+		var stored struct {
+			ID bson.RawValue `bson:"_id"`
+		}
+		document := kb.NewDocument()
+		if err := cursor.Decode(&stored); err != nil {
+			return err
+		}
+		if err := cursor.Decode(document); err != nil {
+			return fmt.Errorf("failed to decode %s: %v", stored.ID, err)
+		}
+		ids = append(ids, stored.ID)
+		documents = append(documents, document)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("Found %d documents in %s.%s without a %d dimension embedding\n", len(documents), kb.Db, active, provenance.Dimensions)
+	if dryRun || len(documents) == 0 {
+		for i, id := range ids {
+			if i == maxPlannedIDs {
+				fmt.Printf("  ... and %d more\n", len(ids)-maxPlannedIDs)
+				break
+			}
+			fmt.Printf("  %s\n", id)
+		}
+		return nil
+	}
+
+	adapter := format.Adapter()
+	limiter := embedding.CapConcurrency(embedding.NewSteadyRateLimiter(2500, time.Minute, concurrency), concurrency)
+	embedder := embedding.NewService(generator, adapter, limiter)
+	var bar *embedding.ProgressBar
+	if uploadProgress {
+		bar = embedding.NewProgressBar(os.Stderr)
+		embedder.Observer = bar
+	}
+	result, err := embedder.GenerateEmbeddings(ctx, documents)
+	if bar != nil {
+		bar.Finish()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %v", err)
+	}
+	failed := make(map[int]bool, len(result.Failed))
+	for _, failure := range result.Failed {
+		failed[failure.Index] = true
+	}
+
+	reader := adapter.(embedding.EmbeddingReader[interface{}])
+	models := make([]mongo.WriteModel, 0, len(documents))
+	for i, document := range documents {
+		if failed[i] {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: ids[i]}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: kb.Path, Value: reader.GetEmbedding(document)}}}}))
+	}
+	updated := int64(0)
+	for start := 0; start < len(models); start += uploadBatchSize {
+		end := min(start+uploadBatchSize, len(models))
+		written, err := collection.BulkWrite(ctx, models[start:end], options.BulkWrite().SetOrdered(false))
+		if written != nil {
+			updated += written.ModifiedCount
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update embeddings: %v", err)
+		}
+	}
+	fmt.Printf("Updated %d of %d documents\n", updated, len(documents))
+	if entry == nil || entry.Provenance.Model == "" {
+		recordProvenance(client, kb, provenance)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d documents failed to embed", len(result.Failed))
+	}
+	return nil
+}
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Embed documents that are stored without an embedding of the right size",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		kb, ok := nvoke.KnowledgeBases[persona]
+		if !ok {
+			log.Fatalf("Invalid persona %q\n", persona)
+		}
+		if concurrency < 1 {
+			log.Fatalf("Invalid concurrency %d\n", concurrency)
+		}
+		format, err := resolveFormat()
+		if err != nil {
+			log.Fatalf("Error backfilling %s: %v\n", persona, err)
+		}
+
+		clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+		client, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer client.Disconnect(ctx)
+
+		if err := Backfill(ctx, client, kb, format, dryRun); err != nil {
+			log.Fatalf("Error backfilling %s: %v\n", persona, err)
+		}
+	},
+}
+
+func init() {
+	backfillCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose knowledge base is backfilled")
+	backfillCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format of the stored documents, defaults to the format of the persona")
+	backfillCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the documents without embedding or changing anything")
+	backfillCmd.Flags().IntVar(&concurrency, "concurrency", 4, "Maximum number of embedding requests in flight")
+	backfillCmd.Flags().BoolVar(&uploadProgress, "progress", true, "Show a progress bar")
+	rootCmd.AddCommand(backfillCmd)
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/embedding"
+	"os"
+)
+
+// resolveFormat returns the format named by --format, falling back to the
+// default format of --persona. A format must belong to --persona when both
+// are given, so that one persona's documents never reach another's
+// knowledge base.
+func resolveFormat() (nvoke.Format, error) {
+	if formatName == "" {
+		return nvoke.PersonaFormat(persona)
+	}
+	format, err := nvoke.LookupFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+	if persona != "" && format.Persona() != persona {
+		return nil, fmt.Errorf("format %s holds %s documents, not %s documents", format.Name(), format.Persona(), persona)
+	}
+	return format, nil
+}
+
+// embeddingsWriter streams documents to an embeddings file as they are
+// embedded. Documents are written next to output and only moved over it by
+// Commit, so an interrupted run never leaves a truncated file where an
+// embeddings file is expected.
+type embeddingsWriter struct {
+	output    string
+	temporary string
+	file      *os.File
+	writer    *embedding.FileWriter
+}
+
+// createEmbeddings starts an embeddings file for output with header.
+func createEmbeddings(output string, header embedding.FileHeader) (*embeddingsWriter, error) {
+	temporary := output + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", output, err)
+	}
+	writer, err := embedding.NewFileWriter(file, header)
+	if err != nil {
+		file.Close()
+		os.Remove(temporary)
+		return nil, err
+	}
+	return &embeddingsWriter{output: output, temporary: temporary, file: file, writer: writer}, nil
+}
+
+// Write appends document and flushes it to disk. It is safe to call from
+// several goroutines at once.
+func (w *embeddingsWriter) Write(document interface{}) error {
+	if err := w.writer.Write(document); err != nil {
+		return fmt.Errorf("failed to write %s: %v", w.output, err)
+	}
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", w.output, err)
+	}
+	return nil
+}
+
+// Commit closes the file and moves it over output.
+func (w *embeddingsWriter) Commit() error {
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.temporary)
+		return fmt.Errorf("failed to write %s: %v", w.output, err)
+	}
+	return os.Rename(w.temporary, w.output)
+}
+
+// Abort closes and deletes the unfinished file, leaving output untouched.
+func (w *embeddingsWriter) Abort() {
+	w.file.Close()
+	os.Remove(w.temporary)
+}
+
+// writeEmbeddings writes documents to output as an embeddings file.
+func writeEmbeddings[T any](output string, header embedding.FileHeader, documents []T) error {
+	writer, err := createEmbeddings(output, header)
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		if err := writer.writer.Write(document); err != nil {
+			writer.Abort()
+			return fmt.Errorf("failed to write %s: %v", output, err)
+		}
+	}
+	if err := writer.writer.Flush(); err != nil {
+		writer.Abort()
+		return fmt.Errorf("failed to write %s: %v", output, err)
+	}
+	return writer.Commit()
+}
+
+// readEmbeddings reads an embeddings file written by generate for format, or
+// a legacy JSON array of its documents. The provenance comes from the file's
+// header, or from the sidecar of a legacy file, and is nil when neither
+// records it.
+func readEmbeddings(format nvoke.Format, path string) ([]interface{}, *embedding.Provenance, error) {
+	documents := make([]interface{}, 0)
+	provenance, err := forEachEmbedding(format, path, func(document interface{}) error {
+		documents = append(documents, document)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return documents, provenance, nil
+}
+
+// forEachEmbedding calls fn with each document of the embeddings file at
+// path as it is read, without holding the whole file in memory, and returns
+// the provenance as readEmbeddings does. An error from fn stops the read.
+func forEachEmbedding(format nvoke.Format, path string, fn func(document interface{}) error) (*embedding.Provenance, error) {
+	file, err := openEmbeddings(format, path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	for {
+		document, err := file.Next()
+		if errors.Is(err, io.EOF) {
+			return file.Provenance, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(document); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// embeddingsFile reads the documents of an embeddings file for a format one
+// at a time. Provenance comes from the file's header, or from the sidecar of
+// a legacy file, and is nil when neither records it.
+type embeddingsFile struct {
+	Provenance *embedding.Provenance
+
+	format nvoke.Format
+	path   string
+	file   *os.File
+	reader *embedding.FileReader
+}
+
+// openEmbeddings opens the embeddings file at path, checking that its header
+// describes documents of format.
+func openEmbeddings(format nvoke.Format, path string) (*embeddingsFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := embedding.NewFileReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	embeddings := &embeddingsFile{format: format, path: path, file: file, reader: reader}
+	if header := reader.Header(); header != nil {
+		if header.Persona != "" && header.Persona != format.Persona() {
+			file.Close()
+			return nil, fmt.Errorf("%s holds %s documents, not %s", path, header.Persona, format.Persona())
+		}
+		if header.Parser == format.Name() && header.ParserVersion != format.Version() {
+			log.Printf("%s was parsed with version %d of the %s parser, the current version is %d\n", path, header.ParserVersion, header.Parser, format.Version())
+		}
+		embeddings.Provenance = &header.Provenance
+	} else if legacy, err := readProvenance(provenancePath(path)); err == nil {
+		embeddings.Provenance = &legacy
+	}
+	return embeddings, nil
+}
+
+// Next returns the next document, or io.EOF after the last one.
+func (f *embeddingsFile) Next() (interface{}, error) {
+	document := f.format.NewDocument()
+	if err := f.reader.Next(document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to decode %s: %v", f.path, err)
+	}
+	return document, nil
+}
+
+func (f *embeddingsFile) Close() error {
+	return f.file.Close()
+}
+
+// countDocuments estimates the number of documents in the embeddings file at
+// path for progress reports from its lines, one per document after the
+// header. Legacy JSON arrays are not written a line per document, so the
+// estimate for them is zero.
+func countDocuments(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	lines := 0
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buffer)
+		lines += bytes.Count(buffer[:n], []byte{'\n'})
+		if err != nil {
+			return max(lines-1, 0)
+		}
+	}
+}
+
+// embeddingsPath returns the default embeddings file of format, falling back
+// to the legacy JSON array written before embeddings files existed.
+func embeddingsPath(format nvoke.Format) string {
+	path := format.Output()
+	legacy := format.LegacyOutput()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && legacy != "" {
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy
+		}
+	}
+	return path
+}
+
+// loadEmbeddings reads the documents that generate wrote for persona along
+// with their vectors and the provenance of the file, if it records one.
+// Documents without an embedding are skipped.
+func loadEmbeddings(persona string, path string) ([]interface{}, [][]float32, *embedding.Provenance, error) {
+	items := make([]interface{}, 0)
+	vectors := make([][]float32, 0)
+	provenance, err := forEachVector(persona, path, func(document interface{}, vector []float32) error {
+		items = append(items, document)
+		vectors = append(vectors, vector)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return items, vectors, provenance, nil
+}
+
+// loadIndex reads the documents that generate wrote for persona into an
+// index of their vectors in encoding, as loadEmbeddings does. Each vector is
+// added to the index as it is read and only its encoded form is kept, so the
+// full precision vectors are never all held in memory.
+func loadIndex(persona string, path string, encoding embedding.Encoding, rescore int) ([]interface{}, *embedding.Index, *embedding.Provenance, error) {
+	index, err := embedding.NewIndex(encoding, nil, rescore)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	items := make([]interface{}, 0)
+	provenance, err := forEachVector(persona, path, func(document interface{}, vector []float32) error {
+		items = append(items, document)
+		return index.Add(vector)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return items, index, provenance, nil
+}
+
+// forEachVector calls fn with each document of persona's embeddings file at
+// path, or its default file, that has an embedding. The embedding is passed
+// to fn and removed from the document.
+func forEachVector(persona string, path string, fn func(document interface{}, vector []float32) error) (*embedding.Provenance, error) {
+	format, err := nvoke.PersonaFormat(persona)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = embeddingsPath(format)
+	}
+	adapter := format.Adapter()
+	reader, ok := adapter.(embedding.EmbeddingReader[interface{}])
+	if !ok {
+		return nil, fmt.Errorf("adapter for %s cannot read embeddings", format.Name())
+	}
+	return forEachEmbedding(format, path, func(document interface{}) error {
+		vector := reader.GetEmbedding(document)
+		if len(vector) == 0 {
+			return nil
+		}
+		adapter.StoreEmbedding(document, nil)
+		return fn(document, vector)
+	})
+}
+
+// prepareEmbedding checks that document, identified by id, has an embedding
+// of the dimensions recorded in provenance and shortens it to the dimensions
+// configured for kb.
+func prepareEmbedding[T any](adapter embedding.Adapter[T], document T, id string, kb nvoke.KnowledgeBase, provenance embedding.Provenance) error {
+	reader, ok := adapter.(embedding.EmbeddingReader[T])
+	if !ok {
+		return nil
+	}
+	vector := reader.GetEmbedding(document)
+	if len(vector) != provenance.Dimensions {
+		return fmt.Errorf("%s does not have a %d dimension embedding, it has %d dimensions", id, provenance.Dimensions, len(vector))
+	}
+	if kb.Dimensions > 0 && kb.Dimensions < provenance.Dimensions {
+		adapter.StoreEmbedding(document, embedding.Truncate(vector, kb.Dimensions))
+	}
+	return nil
+}
+
+// truncatedProvenance returns the provenance of embeddings recorded with
+// provenance once prepareEmbedding has shortened them for kb.
+func truncatedProvenance(kb nvoke.KnowledgeBase, provenance embedding.Provenance) embedding.Provenance {
+	if kb.Dimensions <= 0 || kb.Dimensions >= provenance.Dimensions {
+		return provenance
+	}
+	fmt.Printf("Truncating embeddings from %d to %d dimensions\n", provenance.Dimensions, kb.Dimensions)
+	provenance.Dimensions = kb.Dimensions
+	return provenance
+}
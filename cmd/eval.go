@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"nvoke/pkg/embedding"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var evalInput string
+var evalQueries int
+var evalK int
+var evalRescore int
+var evalDimensions []int
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate search quality trade-offs on a generated corpus",
+}
+
+var evalQuantizationCmd = &cobra.Command{
+	Use:   "quantization",
+	Short: "Compare the recall and size of quantized vector encodings against full precision search",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, vectors, _, err := loadEmbeddings(persona, evalInput)
+		if err != nil {
+			log.Fatalf("Failed to load embeddings: %v\n", err)
+		}
+		corpus, queries := holdOut(vectors, evalQueries)
+		exact, err := embedding.NewIndex(embedding.EncodingFloat32, corpus, 0)
+		if err != nil {
+			log.Fatalf("Failed to build index: %v\n", err)
+		}
+		fmt.Printf("%d vectors, %d held out queries\n", len(corpus), len(queries))
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(writer, "encoding\tbytes/vector\tsize\tcompression\trecall@%d\trecall@%d rescore %d\n", evalK, evalK, evalRescore)
+		for _, encoding := range embedding.Encodings {
+			index, err := embedding.NewIndex(encoding, corpus, 0)
+			if err != nil {
+				log.Fatalf("Failed to build %s index: %v\n", encoding, err)
+			}
+			rescored, err := embedding.NewIndex(encoding, corpus, evalRescore)
+			if err != nil {
+				log.Fatalf("Failed to build %s index: %v\n", encoding, err)
+			}
+			recall, err := embedding.EvaluateRecall(exact, index, queries, evalK)
+			if err != nil {
+				log.Fatalf("Failed to evaluate %s index: %v\n", encoding, err)
+			}
+			rescoredRecall, err := embedding.EvaluateRecall(exact, rescored, queries, evalK)
+			if err != nil {
+				log.Fatalf("Failed to evaluate %s index: %v\n", encoding, err)
+			}
+			fmt.Fprintf(writer, "%s\t%d\t%s\t%.1fx\t%.4f\t%.4f\n",
+				encoding,
+				index.Size()/index.Len(),
+				formatBytes(index.Size()),
+				float64(exact.Size())/float64(index.Size()),
+				recall,
+				rescoredRecall,
+			)
+		}
+		writer.Flush()
+	},
+}
+
+var evalDimensionsCmd = &cobra.Command{
+	Use:   "dimensions",
+	Short: "Compare the recall and size of truncated embeddings against the full size vectors",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, vectors, _, err := loadEmbeddings(persona, evalInput)
+		if err != nil {
+			log.Fatalf("Failed to load embeddings: %v\n", err)
+		}
+		corpus, queries := holdOut(vectors, evalQueries)
+		exact, err := embedding.NewIndex(embedding.EncodingFloat32, corpus, 0)
+		if err != nil {
+			log.Fatalf("Failed to build index: %v\n", err)
+		}
+		fmt.Printf("%d vectors of %d dimensions, %d held out queries\n", len(corpus), len(corpus[0]), len(queries))
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(writer, "dimensions\tbytes/vector\tsize\tcompression\trecall@%d\n", evalK)
+		for _, size := range evalDimensions {
+			if size <= 0 || size > len(corpus[0]) {
+				log.Printf("Skipping %d dimensions\n", size)
+				continue
+			}
+			index, err := embedding.NewIndex(embedding.EncodingFloat32, truncateAll(corpus, size), 0)
+			if err != nil {
+				log.Fatalf("Failed to build index: %v\n", err)
+			}
+			recall := 0.0
+			for _, query := range queries {
+				expected, err := exact.Search(query, evalK)
+				if err != nil {
+					log.Fatalf("Failed to search: %v\n", err)
+				}
+				actual, err := index.Search(embedding.Truncate(query, size), evalK)
+				if err != nil {
+					log.Fatalf("Failed to search %d dimensions: %v\n", size, err)
+				}
+				recall += embedding.Recall(expected, actual)
+			}
+			fmt.Fprintf(writer, "%d\t%d\t%s\t%.1fx\t%.4f\n",
+				size,
+				index.Size()/index.Len(),
+				formatBytes(index.Size()),
+				float64(exact.Size())/float64(index.Size()),
+				recall/float64(len(queries)),
+			)
+		}
+		writer.Flush()
+	},
+}
+
+func truncateAll(vectors [][]float32, dimensions int) [][]float32 {
+	truncated := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		truncated[i] = embedding.Truncate(vector, dimensions)
+	}
+	return truncated
+}
+
+// holdOut splits every nth vector off as a query so that queries never match
+// themselves in the corpus.
+func holdOut(vectors [][]float32, queries int) ([][]float32, [][]float32) {
+	if queries <= 0 || queries >= len(vectors) {
+		log.Fatalf("Cannot hold out %d queries from %d vectors\n", queries, len(vectors))
+	}
+	step := len(vectors) / queries
+	corpus := make([][]float32, 0, len(vectors)-queries)
+	held := make([][]float32, 0, queries)
+	for i, vector := range vectors {
+		if i%step == 0 && len(held) < queries {
+			held = append(held, vector)
+		} else {
+			corpus = append(corpus, vector)
+		}
+	}
+	return corpus, held
+}
+
+func formatBytes(size int) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+func init() {
+	evalCmd.PersistentFlags().StringVarP(&persona, "persona", "p", "", "The persona whose embeddings are evaluated")
+	evalCmd.PersistentFlags().StringVarP(&evalInput, "input", "i", "", "Embeddings file produced by generate")
+	evalCmd.PersistentFlags().IntVar(&evalQueries, "queries", 200, "Number of corpus vectors held out as queries")
+	evalCmd.PersistentFlags().IntVarP(&evalK, "k", "k", 10, "Number of results compared for recall")
+	evalQuantizationCmd.Flags().IntVar(&evalRescore, "rescore", 50, "Candidates rescored at half precision")
+	evalDimensionsCmd.Flags().IntSliceVar(&evalDimensions, "dims", []int{256, 512, 1024, 1536}, "Truncated sizes to compare")
+	evalCmd.AddCommand(evalQuantizationCmd)
+	evalCmd.AddCommand(evalDimensionsCmd)
+	rootCmd.AddCommand(evalCmd)
+}
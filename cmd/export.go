@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/embedding"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var matrixFormat string
+var exportInput string
+var exportOutput string
+var fromStore bool
+var importForce bool
+var exportEncoding string
+
+// matrixExtensions are the default file extensions of each matrix format.
+var matrixExtensions = map[string]string{
+	embedding.MatrixNPY:     ".npy",
+	embedding.MatrixFloat32: ".f32",
+}
+
+// metadataPath is the JSONL file of document IDs and metadata whose lines
+// after the header align with the rows of the matrix at path.
+func metadataPath(path string) string {
+	return sidecarPath(path, ".meta.jsonl")
+}
+
+// Export writes the embeddings of documents as a matrix in format to output,
+// and the documents without their embeddings, along with their IDs, to the
+// metadata file next to it. Documents without an embedding are left out, and
+// nothing is written unless the remaining embeddings share one length. The
+// values of the matrix are stored in encoding.
+func Export(kb nvoke.KnowledgeBase, format nvoke.Format, documents []interface{}, header embedding.FileHeader, matrix string, encoding embedding.Encoding, output string) error {
+	adapter := format.Adapter()
+	reader, ok := adapter.(embedding.EmbeddingReader[interface{}])
+	if !ok {
+		return fmt.Errorf("adapter for %s cannot read embeddings", format.Name())
+	}
+	identifier, ok := adapter.(embedding.Identifier[interface{}])
+	if !ok {
+		return fmt.Errorf("%s documents have no stable IDs to export them by", format.Name())
+	}
+
+	records := make([]map[string]json.RawMessage, 0, len(documents))
+	vectors := make([][]float32, 0, len(documents))
+	for _, document := range documents {
+		vector := reader.GetEmbedding(document)
+		if len(vector) == 0 {
+			continue
+		}
+		record, err := metadataRecord(kb, identifier.GetID(document), document)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		vectors = append(vectors, vector)
+	}
+	if _, err := embedding.MatrixColumns(vectors); err != nil {
+		return fmt.Errorf("embeddings of %s do not form a matrix: %v", format.Name(), err)
+	}
+	// Check the matrix format and encoding before creating either file.
+	if err := embedding.WriteMatrix(io.Discard, matrix, encoding, nil); err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := embedding.WriteMatrix(file, matrix, encoding, vectors); err != nil {
+		return fmt.Errorf("failed to write %s: %v", output, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	metadata, err := os.Create(metadataPath(output))
+	if err != nil {
+		return err
+	}
+	defer metadata.Close()
+	writer, err := embedding.NewFileWriter(metadata, header)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if skipped := len(documents) - len(vectors); skipped > 0 {
+		fmt.Printf("Skipped %d documents without an embedding\n", skipped)
+	}
+	fmt.Printf("Exported %d embeddings to %s and their metadata to %s\n", len(vectors), output, metadataPath(output))
+	return metadata.Close()
+}
+
+// metadataRecord is the JSON encoding of document with its ID and without
+// its embedding.
+func metadataRecord(kb nvoke.KnowledgeBase, id string, document interface{}) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return nil, err
+	}
+	delete(record, kb.Path)
+	if record["id"], err = json.Marshal(id); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Import reads a matrix exported in format from path with its metadata file
+// and writes the documents with their embeddings restored to output as an
+// embeddings file.
+func Import(format nvoke.Format, matrix string, path string, output string) error {
+	documents, provenance, err := readEmbeddings(format, metadataPath(path))
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	vectors, err := embedding.ReadMatrix(file, matrix, len(documents))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(vectors) != len(documents) {
+		return fmt.Errorf("%s has %d rows but %s describes %d documents", path, len(vectors), metadataPath(path), len(documents))
+	}
+
+	header := embedding.FileHeader{Persona: format.Persona(), Parser: format.Name(), ParserVersion: format.Version()}
+	if provenance != nil {
+		header.Provenance = *provenance
+	}
+	if len(vectors) > 0 && header.Dimensions != 0 && header.Dimensions != len(vectors[0]) {
+		log.Printf("%s has %d columns, not the %d dimensions recorded in its metadata\n", path, len(vectors[0]), header.Dimensions)
+	}
+	if len(vectors) > 0 {
+		header.Dimensions = len(vectors[0])
+	}
+	adapter := format.Adapter()
+	for i, document := range documents {
+		adapter.StoreEmbedding(document, vectors[i])
+	}
+	if err := writeEmbeddings(output, header, documents); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d embeddings to %s\n", len(documents), output)
+	return nil
+}
+
+// storedEmbeddings reads every document of the active collection kb serves
+// queries from, along with the provenance recorded in its catalog. The name
+// of the active collection is returned even when reading it fails.
+func storedEmbeddings(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase) (string, []interface{}, *embedding.Provenance, error) {
+	catalog := nvoke.NewCatalog(client)
+	active, err := catalog.ActiveCollection(ctx, kb)
+	if err != nil {
+		return kb.Collection, nil, nil, fmt.Errorf("failed to read catalog: %v", err)
+	}
+	var provenance *embedding.Provenance
+	entry, err := catalog.Get(ctx, kb)
+	switch {
+	case err == nil:
+		provenance = &entry.Provenance
+	case !errors.Is(err, nvoke.ErrNotCataloged):
+		return active, nil, nil, err
+	}
+	cursor, err := client.Database(kb.Db).Collection(active).Find(ctx, bson.D{})
+	if err != nil {
+		return active, nil, nil, err
+	}
+	defer cursor.Close(ctx)
+	documents := make([]interface{}, 0)
+	for cursor.Next(ctx) {
+		document := kb.NewDocument()
+		if err := cursor.Decode(document); err != nil {
+			return active, nil, nil, err
+		}
+		documents = append(documents, document)
+	}
+	return active, documents, provenance, cursor.Err()
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export corpus embeddings as a NumPy or raw float32 matrix with aligned metadata",
+	Run: func(cmd *cobra.Command, args []string) {
+		kb, format := matrixCommandFormat()
+		var documents []interface{}
+		var provenance *embedding.Provenance
+		var err error
+		source := exportInput
+		if fromStore {
+			ctx := context.Background()
+			client, err := mongo.Connect(ctx, options.Client().ApplyURI(MongoDBConnectionString))
+			if err != nil {
+				log.Fatalf("Failed to connect to MongoDB: %v", err)
+			}
+			defer client.Disconnect(ctx)
+			var active string
+			active, documents, provenance, err = storedEmbeddings(ctx, client, kb)
+			if err != nil {
+				log.Fatalf("Error reading %s.%s: %v\n", kb.Db, active, err)
+			}
+			source = format.Output()
+		} else {
+			if source == "" {
+				source = embeddingsPath(format)
+			}
+			if documents, provenance, err = readEmbeddings(format, source); err != nil {
+				log.Fatalf("Error reading %s: %v\n", source, err)
+			}
+		}
+
+		output := exportOutput
+		if output == "" {
+			output = sidecarPath(source, matrixExtensions[matrixFormat])
+		}
+		header := embedding.FileHeader{Persona: format.Persona(), Parser: format.Name(), ParserVersion: format.Version()}
+		if provenance != nil {
+			header.Provenance = *provenance
+		}
+		if err := Export(kb, format, documents, header, matrixFormat, embedding.Encoding(exportEncoding), output); err != nil {
+			log.Fatalf("Error exporting %s: %v\n", persona, err)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a matrix written by export back into an embeddings file",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, format := matrixCommandFormat()
+		input := exportInput
+		if input == "" {
+			input = sidecarPath(format.Output(), matrixExtensions[matrixFormat])
+		}
+		output := exportOutput
+		if output == "" {
+			output = sidecarPath(input, ".imported.jsonl")
+		}
+		if _, err := os.Stat(output); err == nil && !importForce {
+			log.Fatalf("%s already exists, pass --force to overwrite it\n", output)
+		}
+		if err := Import(format, matrixFormat, input, output); err != nil {
+			log.Fatalf("Error importing %s: %v\n", input, err)
+		}
+	},
+}
+
+// matrixCommandFormat validates the flags shared by export and import and
+// returns the knowledge base and corpus format of --persona.
+func matrixCommandFormat() (nvoke.KnowledgeBase, nvoke.Format) {
+	kb, ok := nvoke.KnowledgeBases[persona]
+	if !ok {
+		log.Fatalf("Invalid persona %q\n", persona)
+	}
+	if _, ok := matrixExtensions[matrixFormat]; !ok {
+		log.Fatalf("Invalid matrix format %q, expected %s or %s\n", matrixFormat, embedding.MatrixNPY, embedding.MatrixFloat32)
+	}
+	format, err := nvoke.PersonaFormat(persona)
+	if err != nil {
+		log.Fatalf("%v\n", err)
+	}
+	return kb, format
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose embeddings are exported")
+	exportCmd.Flags().StringVar(&matrixFormat, "format", embedding.MatrixNPY, "Matrix format: npy or f32bin")
+	exportCmd.Flags().StringVarP(&exportInput, "input", "i", "", "Embeddings file produced by generate, defaulting to the format's output")
+	exportCmd.Flags().StringVar(&exportEncoding, "encoding", string(embedding.EncodingFloat32), "Matrix values: float32, or float16 for npy")
+	exportCmd.Flags().BoolVar(&fromStore, "from-store", false, "Export the documents stored in MongoDB instead of a generated file")
+	exportCmd.Flags().StringVarP(&exportOutput, "out", "o", "", "Matrix file to write, defaulting to the input path with the format's extension")
+	rootCmd.AddCommand(exportCmd)
+
+	importCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose embeddings are imported")
+	importCmd.Flags().StringVar(&matrixFormat, "format", embedding.MatrixNPY, "Matrix format: npy or f32bin")
+	importCmd.Flags().StringVarP(&exportInput, "input", "i", "", "Matrix file written by export, read along with its .meta.jsonl file")
+	importCmd.Flags().StringVarP(&exportOutput, "out", "o", "", "Embeddings file to write, defaulting to the input path with .imported.jsonl")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Overwrite the embeddings file if it already exists")
+	rootCmd.AddCommand(importCmd)
+}
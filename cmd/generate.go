@@ -4,76 +4,297 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"nvoke/pkg/bible"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/diagnostic"
 	"nvoke/pkg/embedding"
-	"nvoke/pkg/tao"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
 )
 
-func GenerateAndSaveEmbeddings[T any](adapter embedding.Adapter[T], content []T, writer io.Writer) {
+// Policies for items whose embeddings could not be generated.
+const (
+	FailurePolicyFail  = "fail"
+	FailurePolicyWrite = "write"
+	FailurePolicyRetry = "retry"
+)
+
+var failurePolicy string
+var retries int
+var resume bool
+var checkpointPath string
+var progress bool
+var strict bool
+var formatName string
+var generateInput string
+var generateOutput string
+var generateEncoding string
+
+// failureRecord is the on-disk form of an embedding.Failure.
+type failureRecord struct {
+	Index int         `json:"index"`
+	Item  interface{} `json:"item"`
+	Error string      `json:"error"`
+}
+
+// GenerateAndSaveEmbeddings embeds content and writes it to output as an
+// embeddings file, completing header with the generator's provenance. Each
+// document is written as soon as it is embedded, so documents appear in the
+// order they complete, and the file replaces output once the run succeeds.
+func GenerateAndSaveEmbeddings[T any](adapter embedding.Adapter[T], content []T, output string, header embedding.FileHeader) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// Initialize OpenAI client and necessary components
 	client := openai.NewClient(OpenAIAPIKey)
-	generator := embedding.NewOpenAIGenerator(client, openai.SmallEmbedding3, 1536)
+	generator := newGenerator(client)
 	limiter := embedding.NewSteadyRateLimiter(2500, time.Minute, 10)
 
 	// Create and use the embedding embedder
 	embedder := embedding.NewService(generator, adapter, limiter)
 
-	err := embedder.GenerateEmbeddings(context.Background(), content)
+	// Record each completed item so an interrupted run can be resumed
+	if checkpointPath == "" {
+		checkpointPath = sidecarPath(output, ".checkpoint.jsonl")
+	}
+	header.Provenance = generator.Provenance()
+	checkpoint, err := embedding.NewFileCheckpoint(checkpointPath, resume, header.Provenance)
 	if err != nil {
-		fmt.Printf("Failed to generate embeddings: %v\n", err)
-		return
+		return fmt.Errorf("failed to open checkpoint %s: %v", checkpointPath, err)
 	}
+	defer checkpoint.Close()
+	if resume {
+		if err := checkResumable(checkpoint, header.Provenance, adapter, content); err != nil {
+			return err
+		}
+	}
+	embedder.Checkpoint = checkpoint
 
-	// Save verses with embeddings to JSON
-	bytes, err := json.Marshal(content)
+	writer, err := createEmbeddings(output, header)
 	if err != nil {
-		fmt.Printf("Failed to marshal chapters: %v\n", err)
-		return
+		return err
 	}
+	committed := false
+	defer func() {
+		if !committed {
+			writer.Abort()
+		}
+	}()
+	embedder.Sink = func(item T) error { return writer.Write(item) }
 
-	_, err = writer.Write(bytes)
+	generate := func(items []T) (*embedding.Result[T], error) {
+		if progress {
+			bar := embedding.NewProgressBar(os.Stderr)
+			embedder.Observer = bar
+			defer bar.Finish()
+		}
+		return embedder.GenerateEmbeddings(ctx, items)
+	}
+
+	result, err := generate(content)
 	if err != nil {
-		fmt.Printf("Failed to write to chapters.json: %v\n", err)
+		return fmt.Errorf("failed to generate embeddings, rerun with --resume to continue from %s: %v", checkpointPath, err)
 	}
-	fmt.Println("Embeddings generated and saved to json file")
+	failures := result.Failed
+	if failurePolicy == FailurePolicyRetry {
+		for attempt := 1; attempt <= retries && len(failures) > 0; attempt++ {
+			log.Printf("Retrying %d failed items (attempt %d of %d)\n", len(failures), attempt, retries)
+			retried, err := generate(result.FailedItems())
+			if err != nil {
+				return fmt.Errorf("failed to retry embeddings: %v", err)
+			}
+			// Map indices in the retried subset back onto the original content.
+			remaining := make([]embedding.Failure[T], len(retried.Failed))
+			for i, failure := range retried.Failed {
+				failure.Index = failures[failure.Index].Index
+				remaining[i] = failure
+			}
+			result.Succeeded += retried.Succeeded
+			result.Failed = remaining
+			failures = remaining
+		}
+	}
+	fmt.Printf("Embedded %d of %d items in %s (%d resumed from checkpoint)\n", result.Succeeded+result.Resumed, len(content), result.Elapsed, result.Resumed)
+
+	if len(failures) > 0 {
+		if failurePolicy != FailurePolicyWrite {
+			return fmt.Errorf("%d of %d items failed to embed", len(failures), len(content))
+		}
+		if err := writeFailures(failuresPath(output), failures); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d failed items to %s\n", len(failures), failuresPath(output))
+	}
+
+	committed = true
+	if err := writer.Commit(); err != nil {
+		return err
+	}
+	if err := checkpoint.Remove(); err != nil {
+		log.Printf("Failed to remove checkpoint %s: %v\n", checkpointPath, err)
+	}
+	fmt.Printf("Embeddings generated and saved to %s\n", output)
+	return nil
+}
+
+// checkResumable refuses to resume from a checkpoint whose embeddings were
+// not produced with provenance, since they would be written under a header
+// naming another model, or none of whose IDs belong to content, as left
+// behind when a parser changes how it IDs documents, since every item would
+// silently be embedded again.
+func checkResumable[T any](checkpoint *embedding.FileCheckpoint, provenance embedding.Provenance, adapter embedding.Adapter[T], content []T) error {
+	done, err := checkpoint.Load()
+	if err != nil || len(done) == 0 {
+		return err
+	}
+	recorded := checkpoint.Provenance()
+	if recorded == nil {
+		return fmt.Errorf("checkpoint %s does not record which model produced it: run without --resume to start over", checkpointPath)
+	}
+	if !recorded.Matches(provenance) {
+		return fmt.Errorf("checkpoint %s was embedded with %s, not %s: run without --resume to start over, or with the original model and dimensions", checkpointPath, recorded, provenance)
+	}
+	identifier, ok := adapter.(embedding.Identifier[T])
+	if !ok {
+		return nil
+	}
+	for _, item := range content {
+		if _, ok := done[identifier.GetID(item)]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("checkpoint %s holds none of these documents, it may predate a change to their IDs: run without --resume to start over", checkpointPath)
+}
+
+// sidecarPath names a file kept next to output, replacing its extension
+// with suffix.
+func sidecarPath(output string, suffix string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + suffix
+}
+
+// provenancePath is the sidecar file recording which model embedded a legacy
+// JSON array of documents. Embeddings files record it in their header.
+func provenancePath(output string) string {
+	return sidecarPath(output, ".provenance.json")
+}
+
+func readProvenance(path string) (embedding.Provenance, error) {
+	var provenance embedding.Provenance
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return provenance, err
+	}
+	err = json.Unmarshal(bytes, &provenance)
+	return provenance, err
+}
+
+func failuresPath(output string) string {
+	return sidecarPath(output, ".failures.json")
+}
+
+func writeFailures[T any](path string, failures []embedding.Failure[T]) error {
+	records := make([]failureRecord, len(failures))
+	for i, failure := range failures {
+		records[i] = failureRecord{Index: failure.Index, Item: failure.Item, Error: failure.Err.Error()}
+	}
+	bytes, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal failures: %v", err)
+	}
+	if err := os.WriteFile(path, bytes, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+func withoutFailures[T any](content []T, failures []embedding.Failure[T]) []T {
+	failed := make(map[int]bool, len(failures))
+	for _, failure := range failures {
+		failed[failure.Index] = true
+	}
+	kept := make([]T, 0, len(content)-len(failures))
+	for i, item := range content {
+		if !failed[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// parseDocuments parses location with format and prints the diagnostics the
+// parser reported.
+func parseDocuments(format nvoke.Format, location string, mode diagnostic.Mode) ([]interface{}, error) {
+	documents, diagnostics, err := format.Parse(location, mode)
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s source text (%s): %v", format.Name(), diagnostics.Summary(), err)
+	}
+	fmt.Printf("Parsed %d documents with %s\n", len(documents), diagnostics.Summary())
+	return documents, nil
 }
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate embeddings for text",
 	Run: func(cmd *cobra.Command, args []string) {
-		switch persona {
-		case "bible":
-			parser := bible.Parser{}
-			verses := parser.Parse("")
-			file, err := os.Create("texts/bible/nkjv-verses.json")
-			if err != nil {
-				fmt.Printf("Failed to create nkjv-verses.json: %v\n", err)
-				return
-			}
-			defer file.Close()
-			GenerateAndSaveEmbeddings(bible.NewEmbeddingAdapter(), verses, file)
-		case "tao":
-			parser := tao.Parser{}
-			chapters := parser.Parse("")
-			file, err := os.Create("texts/tao/linn/chapters.json")
-			if err != nil {
-				fmt.Printf("Failed to create chapters.json: %v\n", err)
-				return
-			}
-			defer file.Close()
-			GenerateAndSaveEmbeddings(tao.NewEmbeddingAdapter(), chapters, file)
+		switch failurePolicy {
+		case FailurePolicyFail, FailurePolicyWrite, FailurePolicyRetry:
+		default:
+			log.Fatalf("Invalid failure policy %q\n", failurePolicy)
+		}
+		if _, err := embedding.EncodeVector(embedding.Encoding(generateEncoding), nil); err != nil {
+			log.Fatalf("Invalid encoding: %v\n", err)
+		}
+
+		mode := diagnostic.Lenient
+		if strict {
+			mode = diagnostic.Strict
+		}
+
+		format, err := resolveFormat()
+		if err != nil {
+			log.Fatalf("%v (available formats: %s)\n", err, strings.Join(nvoke.FormatNames(), ", "))
+		}
+		input := generateInput
+		if input == "" {
+			input = format.Input()
+		}
+		output := generateOutput
+		if output == "" {
+			output = format.Output()
+		}
+
+		documents, err := parseDocuments(format, input, mode)
+		if err != nil {
+			log.Fatalf("Error generating embeddings: %v\n", err)
+		}
+		header := embedding.FileHeader{Persona: format.Persona(), Parser: format.Name(), ParserVersion: format.Version(), Encoding: embedding.Encoding(generateEncoding)}
+		err = GenerateAndSaveEmbeddings(format.Adapter(), documents, output, header)
+		if err != nil {
+			log.Fatalf("Error generating embeddings: %v\n", err)
 		}
 	},
 }
 
 func init() {
 	generateCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona to use when searching")
+	generateCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format to parse, defaults to the format of the persona")
+	generateCmd.Flags().StringVarP(&generateInput, "input", "i", "", "Source file or directory, defaults to the format's corpus")
+	generateCmd.Flags().StringVarP(&generateOutput, "out", "o", "", "Embeddings file to write, defaults to the format's output")
+	generateCmd.Flags().StringVar(&generateEncoding, "encoding", string(embedding.EncodingFloat32), "How embeddings are stored: float32, or float16 or int8 to shrink the file")
+	generateCmd.Flags().StringVar(&failurePolicy, "on-failure", FailurePolicyFail, "What to do with items that fail to embed: fail, write or retry")
+	generateCmd.Flags().IntVar(&retries, "retries", 3, "Number of times to retry failed items with --on-failure=retry")
+	generateCmd.Flags().BoolVar(&resume, "resume", false, "Resume from the checkpoint left by an interrupted run")
+	generateCmd.Flags().BoolVar(&strict, "strict", false, "Stop at the first malformed element in the source text instead of skipping it")
+	generateCmd.Flags().BoolVar(&progress, "progress", true, "Show a progress bar instead of logging each batch")
+	generateCmd.Flags().StringVar(&checkpointPath, "checkpoint", "", "Checkpoint file (defaults to the output path with a .checkpoint.jsonl suffix)")
 	rootCmd.AddCommand(generateCmd)
 }
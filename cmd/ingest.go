@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/embedding"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ingestInput string
+var dryRun bool
+var prune bool
+
+// maxPlannedIDs caps the IDs printed for each kind of change in a plan.
+const maxPlannedIDs = 20
+
+// Ingest brings the active collection of kb in line with the corpus parsed
+// from location. Only documents that are new or whose content hash changed
+// are embedded and upserted. Stored documents missing from location are only
+// deleted with prune, since a collection can hold several corpora, such as
+// the translations of the Bible, and location may be just one of them. With
+// dryRun the plan is printed and nothing is changed.
+func Ingest(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase, format nvoke.Format, location string, mode diagnostic.Mode, dryRun bool, prune bool) error {
+	documents, err := parseDocuments(format, location, mode)
+	if err != nil {
+		return err
+	}
+	adapter := format.Adapter()
+	identifier, ok := adapter.(embedding.Identifier[interface{}])
+	if !ok {
+		return fmt.Errorf("%s documents have no stable IDs to ingest by", format.Name())
+	}
+	byID := make(map[string]interface{}, len(documents))
+	hashes := make(map[string]string, len(documents))
+	for _, document := range documents {
+		id := identifier.GetID(document)
+		if _, ok := byID[id]; ok {
+			return fmt.Errorf("%s: duplicate document ID", id)
+		}
+		hash, err := nvoke.ContentHash(kb, document)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %v", id, err)
+		}
+		byID[id] = document
+		hashes[id] = hash
+	}
+
+	catalog := nvoke.NewCatalog(client)
+	active, err := catalog.ActiveCollection(ctx, kb)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog: %v", err)
+	}
+	collection := client.Database(kb.Db).Collection(active)
+	stored, err := nvoke.StoredHashes(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s: %v", kb.Db, active, err)
+	}
+	plan := nvoke.PlanIngest(hashes, stored, prune)
+	printPlan(plan)
+	if dryRun || plan.Empty() {
+		return nil
+	}
+
+	// New embeddings must be comparable with the ones already stored.
+	generator := newKnowledgeBaseGenerator(kb)
+	provenance, _ := embedding.Describe(generator)
+	if entry, err := catalog.Get(ctx, kb); err == nil && entry.Provenance.Model != "" && len(stored) > 0 {
+		if !entry.Provenance.Matches(provenance) {
+			return fmt.Errorf("%s.%s was embedded with %s, not %s: regenerate and reindex the whole corpus instead", kb.Db, active, entry.Provenance, provenance)
+		}
+	}
+
+	pending := make([]interface{}, 0, len(plan.Added)+len(plan.Changed))
+	for _, id := range append(append([]string(nil), plan.Added...), plan.Changed...) {
+		pending = append(pending, byID[id])
+	}
+	embedder := embedding.NewService(generator, adapter, embedding.NewSteadyRateLimiter(2500, time.Minute, 10))
+	var bar *embedding.ProgressBar
+	if uploadProgress {
+		bar = embedding.NewProgressBar(os.Stderr)
+		embedder.Observer = bar
+	}
+	embedded, err := embedder.GenerateEmbeddings(ctx, pending)
+	if bar != nil {
+		bar.Finish()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %v", err)
+	}
+	if len(embedded.Failed) > 0 {
+		// Failed documents keep their old hash, so the next ingest retries them.
+		fmt.Printf("%d of %d documents failed to embed and were skipped\n", len(embedded.Failed), len(pending))
+		pending = withoutFailures(pending, embedded.Failed)
+	}
+
+	result, err := upsertDocuments(ctx, collection, kb, adapter, pending)
+	fmt.Println(result)
+	if err != nil {
+		return err
+	}
+	if len(plan.Deleted) > 0 {
+		deleted, err := collection.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: plan.Deleted}}}})
+		if err != nil {
+			return fmt.Errorf("failed to delete removed documents: %v", err)
+		}
+		fmt.Printf("Deleted %d documents\n", deleted.DeletedCount)
+	}
+	if len(stored) == 0 {
+		recordProvenance(client, kb, provenance)
+	}
+	if len(embedded.Failed) > 0 {
+		return fmt.Errorf("%d documents failed to embed", len(embedded.Failed))
+	}
+	return nil
+}
+
+func printPlan(plan nvoke.IngestPlan) {
+	fmt.Printf("Plan: %s\n", plan)
+	if plan.Kept > 0 {
+		fmt.Printf("  %d stored documents are not in the input and are kept, use --prune to delete them\n", plan.Kept)
+	}
+	for _, change := range []struct {
+		label string
+		ids   []string
+	}{{"+", plan.Added}, {"~", plan.Changed}, {"-", plan.Deleted}} {
+		for i, id := range change.ids {
+			if i == maxPlannedIDs {
+				fmt.Printf("  %s ... and %d more\n", change.label, len(change.ids)-maxPlannedIDs)
+				break
+			}
+			fmt.Printf("  %s %s\n", change.label, id)
+		}
+	}
+}
+
+var ingestCmd = &cobra.Command{
+	Use:   "ingest",
+	Short: "Embed and upload only the documents that changed since the last upload",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		kb, ok := nvoke.KnowledgeBases[persona]
+		if !ok {
+			log.Fatalf("Invalid persona %q\n", persona)
+		}
+		format, err := resolveFormat()
+		if err != nil {
+			log.Fatalf("%v (available formats: %s)\n", err, strings.Join(nvoke.FormatNames(), ", "))
+		}
+		input := ingestInput
+		if input == "" {
+			input = format.Input()
+		}
+		mode := diagnostic.Lenient
+		if strict {
+			mode = diagnostic.Strict
+		}
+
+		clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+		client, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer client.Disconnect(ctx)
+
+		if err := Ingest(ctx, client, kb, format, input, mode, dryRun, prune); err != nil {
+			log.Fatalf("Error ingesting %s: %v\n", persona, err)
+		}
+	},
+}
+
+func init() {
+	ingestCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose knowledge base is updated")
+	ingestCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format to parse, defaults to the format of the persona")
+	ingestCmd.Flags().StringVarP(&ingestInput, "input", "i", "", "Source file or directory, defaults to the format's corpus")
+	ingestCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the plan without embedding or changing anything")
+	ingestCmd.Flags().BoolVar(&prune, "prune", false, "Delete stored documents that are not in the input")
+	ingestCmd.Flags().BoolVar(&strict, "strict", false, "Stop at the first malformed element in the source text instead of skipping it")
+	ingestCmd.Flags().BoolVar(&uploadProgress, "progress", true, "Show a progress bar")
+	rootCmd.AddCommand(ingestCmd)
+}
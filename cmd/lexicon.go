@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"nvoke/pkg/bible"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var lexiconCmd = &cobra.Command{
+	Use:   "lexicon <strong's number or lemma>",
+	Short: "Print the verses using an original-language word, such as G26",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		passages, err := loadPassages(corpus)
+		if err != nil {
+			log.Fatalf("Failed to load the Bible corpus: %v\n", err)
+		}
+		if passages.Lexicon().Len() == 0 {
+			log.Fatalf("%s has no Strong's numbers\n", corpus)
+		}
+		entry, verses, err := passages.Word(args[0], translations)
+		if err != nil {
+			log.Fatalf("Failed to find %s: %v\n", args[0], err)
+		}
+		renderings := make([]string, 0, len(entry.Renderings))
+		for _, rendering := range entry.Renderings {
+			renderings = append(renderings, fmt.Sprintf("%s (%d)", rendering.Text, rendering.Count))
+		}
+		fmt.Printf("%s %s: %d occurrences translated as %s\n", entry.Strong, entry.Lemma, entry.Occurrences, strings.Join(renderings, ", "))
+		for _, verse := range verses {
+			fmt.Printf("%s %s %d:%d %s\n", verse.Translation, verse.Book, verse.Chapter, verse.Verse, verse.Text)
+		}
+	},
+}
+
+func init() {
+	lexiconCmd.Flags().StringVar(&corpus, "corpus", bible.DefaultLocation, "Directory of Bible source texts")
+	lexiconCmd.Flags().StringSliceVarP(&translations, "translation", "t", nil, "Translations to print, defaults to the first loaded")
+	rootCmd.AddCommand(lexiconCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/diagnostic"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var lintInput string
+
+var lintCorpusCmd = &cobra.Command{
+	Use:   "lint-corpus",
+	Short: "Check a parsed corpus for missing, duplicate and malformed documents before embedding it",
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := resolveFormat()
+		if err != nil {
+			log.Fatalf("%v (available formats: %s)\n", err, strings.Join(nvoke.FormatNames(), ", "))
+		}
+		input := lintInput
+		if input == "" {
+			input = format.Input()
+		}
+
+		documents, diagnostics, err := format.Parse(input, diagnostic.Lenient)
+		if err != nil {
+			log.Fatalf("Failed to parse %s source text: %v\n", format.Name(), err)
+		}
+		diagnostics = append(diagnostics, format.Lint(documents)...)
+		for _, d := range diagnostics {
+			fmt.Println(d.Error())
+		}
+		fmt.Printf("Checked %d documents: %s\n", len(documents), diagnostics.Summary())
+		if len(diagnostics) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	lintCorpusCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose corpus is checked")
+	lintCorpusCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format to parse, defaults to the format of the persona")
+	lintCorpusCmd.Flags().StringVarP(&lintInput, "input", "i", "", "Source file or directory, defaults to the format's corpus")
+	rootCmd.AddCommand(lintCorpusCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/bible"
+	"nvoke/pkg/scripture"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var corpus string
+
+var lookupCmd = &cobra.Command{
+	Use:   "lookup <reference>",
+	Short: "Print the verses of a scripture reference such as \"John 3:16\" or \"1 Cor 13\"",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		references, err := scripture.Parse(strings.Join(args, " "))
+		if err != nil {
+			log.Fatalf("Failed to parse reference: %v\n", err)
+		}
+		passages, err := loadPassages(corpus)
+		if err != nil {
+			log.Fatalf("Failed to load the Bible corpus: %v\n", err)
+		}
+		verses, err := passages.Lookup(references, translations)
+		if err != nil {
+			log.Fatalf("Failed to find %s: %v\n", strings.Join(args, " "), err)
+		}
+		for _, verse := range verses {
+			fmt.Printf("%s %s %d:%d %s\n", verse.Translation, verse.Book, verse.Chapter, verse.Verse, verse.Text)
+		}
+	},
+}
+
+// loadPassages parses the Bible source texts at location for reference lookups.
+func loadPassages(location string) (*nvoke.Passages, error) {
+	parser := &bible.Parser{}
+	verses, err := parser.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	if diagnostics := parser.Diagnostics(); len(diagnostics) > 0 {
+		log.Printf("Loaded %s with %s, run generate for details\n", location, diagnostics.Summary())
+	}
+	return nvoke.NewPassages(verses), nil
+}
+
+func init() {
+	lookupCmd.Flags().StringVar(&corpus, "corpus", bible.DefaultLocation, "Directory of Bible source texts")
+	lookupCmd.Flags().StringSliceVarP(&translations, "translation", "t", nil, "Translations to print, defaults to the first loaded")
+	rootCmd.AddCommand(lookupCmd)
+}
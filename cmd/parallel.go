@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nvoke/nvoke"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var parallelCmd = &cobra.Command{
+	Use:   "parallel <book> <chapter> <verse>",
+	Short: "Show a verse in every loaded Bible translation",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		chapter, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("Invalid chapter %q\n", args[1])
+		}
+		verse, err := strconv.Atoi(args[2])
+		if err != nil {
+			log.Fatalf("Invalid verse %q\n", args[2])
+		}
+
+		ctx := context.Background()
+		clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+		mongodb, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer mongodb.Disconnect(ctx)
+
+		service := nvoke.NewRetrievalService(mongodb, nil, nil)
+		verses, err := service.Parallel(ctx, args[0], chapter, verse)
+		if err != nil {
+			log.Fatalf("Failed to find %s %d:%d: %v\n", args[0], chapter, verse, err)
+		}
+		for _, v := range verses {
+			fmt.Printf("%s\t%s %d:%d\t%s\n", v.Translation, v.Book, v.Chapter, v.Verse, v.Text)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(parallelCmd)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"nvoke/nvoke"
-	"nvoke/pkg/embedding"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
@@ -27,6 +26,12 @@ func init() {
 	ragCmd.Flags().StringVarP(&query, "query", "q", "", "Text query to search for similar embeddings")
 	ragCmd.Flags().StringVarP(&completionContext, "context", "x", "", "Text context for the completion")
 	ragCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona to use when searching")
+	ragCmd.Flags().StringSliceVarP(&translations, "translation", "t", nil, "Restrict the search to these translations, defaults to all")
+	ragCmd.Flags().StringVar(&testament, "testament", "", "Restrict the search to one testament: OT, NT or DC")
+	ragCmd.Flags().BoolVar(&align, "align", false, "Return every translation of each Tao chapter found")
+	ragCmd.Flags().BoolVar(&wordsOfJesus, "words-of-jesus", false, "Restrict the search to verses spoken by Jesus")
+	ragCmd.Flags().StringSliceVar(&strongs, "strong", nil, "Restrict the search to verses using these Strong's numbers, such as G26")
+	ragCmd.Flags().StringSliceVarP(&books, "book", "b", nil, "Restrict the search to these books or book ranges, such as Romans or Matthew-John")
 	ragCmd.Flags().IntVarP(&limit, "limit", "l", 10, "Max similar vectors limit.")
 	ragCmd.Flags().IntVarP(&candidates, "candidates", "c", 200, "Number of candidates to consider.")
 	rootCmd.AddCommand(ragCmd)
@@ -36,11 +41,17 @@ func RetrievalAugmentedSearch(query string) {
 	ctx := context.Background()
 
 	openaiClient := openai.NewClient(OpenAIAPIKey)
-	generator := embedding.NewOpenAIGenerator(openaiClient, openai.SmallEmbedding3, 1536)
+	generator := newGenerator(openaiClient)
 
 	data := nvoke.Query{
-		Query:   query,
-		Persona: persona,
+		Query:        query,
+		Persona:      persona,
+		Translations: translations,
+		Testament:    testament,
+		Books:        books,
+		Strongs:      strongs,
+		WordsOfJesus: wordsOfJesus,
+		Align:        align,
 	}
 
 	if completionContext != "" {
@@ -55,6 +66,7 @@ func RetrievalAugmentedSearch(query string) {
 	defer mongodb.Disconnect(ctx)
 
 	service := nvoke.NewRetrievalService(mongodb, generator, openaiClient)
+	service.StrictProvenance = strictProvenance
 
 	completion, err := service.CreateChatCompletion(ctx, data)
 	if err != nil {
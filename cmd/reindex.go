@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/embedding"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var reindexInput string
+var reindexVersion string
+var rollback bool
+var listVersions bool
+var indexTimeout time.Duration
+
+const insertBatchSize = 1000
+
+// Reindex loads embedded documents into a new versioned collection, validates
+// it and then atomically makes it the active collection for kb. The previously
+// active collection is kept so it can be restored with --rollback. A new
+// collection that fails before it is cataloged is dropped, so the same
+// version can be loaded again.
+func Reindex(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase, format nvoke.Format, path string) (err error) {
+	catalog := nvoke.NewCatalog(client)
+
+	adapter := format.Adapter()
+	identifier, ok := adapter.(embedding.Identifier[interface{}])
+	if !ok {
+		return fmt.Errorf("%s documents have no stable IDs to store them by", format.Name())
+	}
+	file, err := openEmbeddings(format, path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	provenance := embedding.Provenance{Model: embeddingModel, Dimensions: dimensions}
+	if file.Provenance == nil {
+		log.Printf("No provenance found for %s, assuming %s\n", path, provenance)
+	} else {
+		provenance = *file.Provenance
+	}
+
+	version := nvoke.CollectionVersion{
+		Name:       fmt.Sprintf("%s_%s", kb.Collection, reindexVersion),
+		Provenance: truncatedProvenance(kb, provenance),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if entry, err := catalog.Get(ctx, kb); err == nil {
+		if _, ok := entry.Version(version.Name); ok {
+			return fmt.Errorf("%s: %w", version.Name, nvoke.ErrVersionExists)
+		}
+	}
+	collection := client.Database(kb.Db).Collection(version.Name)
+	if existing, err := collection.EstimatedDocumentCount(ctx); err != nil {
+		return err
+	} else if existing > 0 {
+		return fmt.Errorf("collection %s already contains %d documents", version.Name, existing)
+	}
+	cataloged := false
+	defer func() {
+		if err == nil || cataloged {
+			return
+		}
+		if dropErr := collection.Drop(context.Background()); dropErr != nil {
+			log.Printf("Failed to drop %s.%s: %v\n", kb.Db, version.Name, dropErr)
+			return
+		}
+		fmt.Printf("Dropped the partially loaded %s.%s\n", kb.Db, version.Name)
+	}()
+
+	fmt.Printf("Writing %s to %s.%s\n", path, kb.Db, version.Name)
+	batch := make([]interface{}, 0, insertBatchSize)
+	insert := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := collection.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to insert documents: %v", err)
+		}
+		version.Count += int64(len(batch))
+		// InsertMany may still reference the documents, so start a new batch.
+		batch = make([]interface{}, 0, insertBatchSize)
+		return nil
+	}
+	for {
+		document, err := file.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		id := identifier.GetID(document)
+		if err := prepareEmbedding(adapter, document, id, kb, provenance); err != nil {
+			return err
+		}
+		stored, err := storedDocument(kb, id, document)
+		if err != nil {
+			return err
+		}
+		if batch = append(batch, stored); len(batch) == insertBatchSize {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := insert(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d documents to %s.%s\n", version.Count, kb.Db, version.Name)
+
+	if err := createVectorIndex(ctx, client, kb, version.Name, version.Provenance.Dimensions); err != nil {
+		return fmt.Errorf("failed to create vector index: %v", err)
+	}
+	if err := validateCollection(ctx, collection, kb, version); err != nil {
+		return err
+	}
+	if err := waitForVectorIndex(ctx, collection, kb.Index, indexTimeout); err != nil {
+		return err
+	}
+
+	if err := catalog.AddVersion(ctx, kb, version); err != nil {
+		return err
+	}
+	cataloged = true
+	if err := catalog.Activate(ctx, kb, version.Name); err != nil {
+		return err
+	}
+	fmt.Printf("%s.%s is now served from %s\n", kb.Db, kb.Collection, version.Name)
+	return nil
+}
+
+func validateCollection(ctx context.Context, collection *mongo.Collection, kb nvoke.KnowledgeBase, version nvoke.CollectionVersion) error {
+	count, err := collection.CountDocuments(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	if count != version.Count {
+		return fmt.Errorf("%s has %d documents, expected %d", version.Name, count, version.Count)
+	}
+	invalid, err := collection.CountDocuments(ctx, bson.D{
+		{Key: kb.Path, Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$size", Value: version.Provenance.Dimensions}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%s has %d documents without a %d dimension embedding", version.Name, invalid, version.Provenance.Dimensions)
+	}
+	return nil
+}
+
+func createVectorIndex(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase, collection string, dimensions int) error {
+	fields := bson.A{
+		bson.D{
+			{Key: "type", Value: "vector"},
+			{Key: "path", Value: kb.Path},
+			{Key: "numDimensions", Value: dimensions},
+			{Key: "similarity", Value: "cosine"},
+		},
+	}
+	// Fields that queries filter on must be indexed with the vectors.
+	for _, filter := range kb.Filters {
+		fields = append(fields, bson.D{{Key: "type", Value: "filter"}, {Key: "path", Value: filter}})
+	}
+	command := bson.D{
+		{Key: "createSearchIndexes", Value: collection},
+		{Key: "indexes", Value: bson.A{
+			bson.D{
+				{Key: "name", Value: kb.Index},
+				{Key: "type", Value: "vectorSearch"},
+				{Key: "definition", Value: bson.D{
+					{Key: "fields", Value: fields},
+				}},
+			},
+		}},
+	}
+	return client.Database(kb.Db).RunCommand(ctx, command).Err()
+}
+
+func waitForVectorIndex(ctx context.Context, collection *mongo.Collection, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		cursor, err := collection.SearchIndexes().List(ctx, options.SearchIndexes().SetName(name))
+		if err != nil {
+			return err
+		}
+		var indexes []struct {
+			Queryable bool   `bson:"queryable"`
+			Status    string `bson:"status"`
+		}
+		if err := cursor.All(ctx, &indexes); err != nil {
+			return err
+		}
+		if len(indexes) > 0 && indexes[0].Queryable {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("vector index %s on %s was not ready after %s", name, collection.Name(), timeout)
+		}
+		fmt.Printf("Waiting for vector index %s on %s to become queryable\n", name, collection.Name())
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func printVersions(ctx context.Context, catalog *nvoke.Catalog, kb nvoke.KnowledgeBase) error {
+	entry, err := catalog.Get(ctx, kb)
+	if err != nil {
+		return err
+	}
+	for _, version := range entry.Versions {
+		marker := " "
+		if version.Name == entry.ActiveCollection() {
+			marker = "*"
+		}
+		fmt.Printf("%s %s %s %d documents created %s\n", marker, version.Name, version.Provenance, version.Count, version.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Load embeddings into a new collection version and switch queries over to it",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		kb, ok := nvoke.KnowledgeBases[persona]
+		if !ok {
+			log.Fatalf("Invalid persona %q\n", persona)
+		}
+
+		clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+		client, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer client.Disconnect(ctx)
+		catalog := nvoke.NewCatalog(client)
+
+		switch {
+		case listVersions:
+			err = printVersions(ctx, catalog, kb)
+		case rollback:
+			if err = catalog.Rollback(ctx, kb); err == nil {
+				var entry *nvoke.CatalogEntry
+				if entry, err = catalog.Get(ctx, kb); err == nil {
+					fmt.Printf("%s.%s rolled back to %s\n", kb.Db, kb.Collection, entry.ActiveCollection())
+					if entry.Provenance.Model == "" {
+						fmt.Printf("%s has no recorded provenance, upload its embeddings again to record it\n", entry.ActiveCollection())
+					}
+				}
+			}
+		default:
+			var format nvoke.Format
+			if format, err = resolveFormat(); err == nil {
+				err = Reindex(ctx, client, kb, format, reindexInputOr(embeddingsPath(format)))
+			}
+		}
+		if err != nil {
+			log.Fatalf("Error reindexing %s: %v\n", persona, err)
+		}
+	},
+}
+
+func reindexInputOr(path string) string {
+	if reindexInput != "" {
+		return reindexInput
+	}
+	return path
+}
+
+func init() {
+	reindexCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose knowledge base is reindexed")
+	reindexCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format of the input, defaulting to the persona's format")
+	reindexCmd.Flags().StringVarP(&reindexInput, "input", "i", "", "Embeddings file produced by generate")
+	reindexCmd.Flags().StringVar(&reindexVersion, "version", time.Now().UTC().Format("20060102150405"), "Suffix for the new collection version")
+	reindexCmd.Flags().BoolVar(&rollback, "rollback", false, "Switch back to the previously active collection")
+	reindexCmd.Flags().BoolVar(&listVersions, "list", false, "List the collection versions")
+	reindexCmd.Flags().DurationVar(&indexTimeout, "index-timeout", 10*time.Minute, "How long to wait for the vector index to become queryable")
+	rootCmd.AddCommand(reindexCmd)
+}
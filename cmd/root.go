@@ -3,25 +3,90 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/bible"
+	"nvoke/pkg/embedding"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/sashabaranov/go-openai"
 	"github.com/spf13/cobra"
 )
 
 var OpenAIAPIKey string
 var MongoDBConnectionString string
 
+var embeddingModel string
+var dimensions int
+var strictProvenance bool
+var corporaPath string
+var versifications []string
+
 var limit int
 var candidates int
 var query string
 var persona string
+var translations []string
+var testament string
+var books []string
+var strongs []string
+var wordsOfJesus bool
+var align bool
 
 var rootCmd = &cobra.Command{
 	Use:   "nvoke",
 	Short: "nvoke is a tool to manage embeddings and storage for a variety of texts.",
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&embeddingModel, "embedding-model", string(openai.SmallEmbedding3), "The OpenAI model used to embed text")
+	rootCmd.PersistentFlags().IntVar(&dimensions, "dimensions", 1536, "The number of embedding dimensions")
+	rootCmd.PersistentFlags().BoolVar(&strictProvenance, "strict-provenance", false, "Refuse to search knowledge bases embedded with a different model")
+	rootCmd.PersistentFlags().StringVar(&corporaPath, "corpora", nvoke.DefaultCorporaPath, "JSON file configuring plain text, Markdown and EPUB corpora")
+	rootCmd.PersistentFlags().StringSliceVar(&versifications, "versification", nil, "Versification of a Bible translation that does not follow KJV, such as WLC=Hebrew")
+	cobra.OnInitialize(loadCorpora, loadVersifications)
+}
+
+// loadCorpora registers the corpora configured in the --corpora file as
+// formats and personas.
+func loadCorpora() {
+	if err := nvoke.LoadCorpora(corporaPath); err != nil {
+		log.Fatalf("Error loading corpora: %v", err)
+	}
+}
+
+// loadVersifications selects the versification each Bible translation named
+// by --versification is checked against.
+func loadVersifications() {
+	for _, value := range versifications {
+		translation, name, ok := strings.Cut(value, "=")
+		if !ok || translation == "" {
+			log.Fatalf("Invalid versification %q, expected TRANSLATION=SCHEME\n", value)
+		}
+		versification, ok := bible.LookupVersification(name)
+		if !ok {
+			log.Fatalf("Unknown versification %q (available: %s)\n", name, strings.Join(bible.Versifications(), ", "))
+		}
+		nvoke.SetVersification(translation, versification)
+	}
+}
+
+// newGenerator creates the embedding generator configured by the global flags.
+func newGenerator(client *openai.Client) *embedding.OpenAIGenerator {
+	return embedding.NewOpenAIGenerator(client, openai.EmbeddingModel(embeddingModel), dimensions)
+}
+
+// newKnowledgeBaseGenerator creates the generator configured by the global
+// flags, truncating its embeddings to the dimensions kb stores.
+func newKnowledgeBaseGenerator(kb nvoke.KnowledgeBase) embedding.Generator {
+	var generator embedding.Generator = newGenerator(openai.NewClient(OpenAIAPIKey))
+	if kb.Dimensions > 0 && kb.Dimensions < dimensions {
+		generator = embedding.NewTruncatingGenerator(generator, kb.Dimensions)
+	}
+	return generator
+}
+
 // Execute executes the root command.
 func Execute() {
 	_ = godotenv.Load()
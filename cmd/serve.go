@@ -7,7 +7,10 @@ import (
 	"log"
 	"net/http"
 	"nvoke/nvoke"
+	"nvoke/pkg/bible"
 	"nvoke/pkg/embedding"
+	"nvoke/pkg/scripture"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -31,12 +34,15 @@ var serveCmd = &cobra.Command{
 
 var port int
 var address string
+var metricsAddress string
 
 func init() {
 	serveCmd.Flags().IntVarP(&limit, "limit", "l", 10, "Max similar vectors limit.")
 	serveCmd.Flags().IntVarP(&candidates, "candidates", "c", 200, "Number of candidates to consider.")
 	serveCmd.Flags().IntVarP(&port, "port", "p", 80, "Listener port")
 	serveCmd.Flags().StringVarP(&address, "address", "a", "0.0.0.0", "Listener address")
+	serveCmd.Flags().StringVar(&metricsAddress, "metrics-address", "", "Address of a separate admin listener serving embedding metrics at /debug/vars, off when empty")
+	serveCmd.Flags().StringVar(&corpus, "corpus", bible.DefaultLocation, "Directory of Bible source texts for reference lookups")
 
 	rootCmd.AddCommand(serveCmd)
 }
@@ -45,7 +51,8 @@ func serve() {
 	r := chi.NewRouter()
 	ctx := context.Background()
 	openaiClient := openai.NewClient(OpenAIAPIKey)
-	generator := embedding.NewOpenAIGenerator(openaiClient, openai.SmallEmbedding3, 1536)
+	metrics := embedding.NewMetrics()
+	generator := embedding.NewObservedGenerator(newGenerator(openaiClient), metrics)
 
 	clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
 	mongodb, err := mongo.Connect(ctx, clientOptions)
@@ -55,6 +62,13 @@ func serve() {
 	}
 	defer mongodb.Disconnect(ctx)
 	service := nvoke.NewRetrievalService(mongodb, generator, openaiClient)
+	service.StrictProvenance = strictProvenance
+	passages, err := loadPassages(corpus)
+	if err != nil {
+		log.Printf("Failed to load the Bible corpus, reference lookups are disabled: %v\n", err)
+	} else {
+		service.Passages = passages
+	}
 
 	// c := cors.New(cors.Options{
 	// 	AllowedOrigins: []string{"http://frontend.local"},
@@ -63,6 +77,10 @@ func serve() {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
+	if metricsAddress != "" {
+		go serveMetrics(metricsAddress, metrics)
+	}
+
 	r.Post("/v1/completion", func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -74,16 +92,20 @@ func serve() {
 		}
 		completion, err := service.CreateChatCompletion(ctx, data)
 		switch err {
+		case nil:
 		case nvoke.ErrInvalidQueryParameters:
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 			return
 		case nvoke.ErrSimilaritySearchFailed,
 			nvoke.ErrEmbeddingGenerationFailed,
+			nvoke.ErrEmbeddingModelMismatch,
 			nvoke.ErrChatCompletionContextBuildFailed,
 			nvoke.ErrChatCompletionFailed:
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
 		default:
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -92,6 +114,98 @@ func serve() {
 		})
 	})
 
+	r.Post("/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		var data nvoke.Query
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			log.Printf("Failed decode request body: %v\n", err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		results, err := service.SemanticSearch(r.Context(), data)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": results,
+		})
+	})
+
+	r.Get("/v1/bible/parallel", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		chapter, _ := strconv.Atoi(params.Get("chapter"))
+		verse, _ := strconv.Atoi(params.Get("verse"))
+		verses, err := service.Parallel(r.Context(), params.Get("book"), chapter, verse)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"verses": verses,
+		})
+	})
+
+	r.Get("/v1/tao/aligned", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		chapter, _ := strconv.Atoi(params.Get("chapter"))
+		chapters, err := service.Aligned(r.Context(), chapter, params["translation"])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"chapters": chapters,
+		})
+	})
+
+	r.Get("/v1/bible/passages", func(w http.ResponseWriter, r *http.Request) {
+		if service.Passages == nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		params := r.URL.Query()
+		references, err := scripture.Parse(params.Get("ref"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		verses, err := service.Passages.Lookup(references, params["translation"])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		names := make([]string, len(references))
+		for i, reference := range references {
+			names[i] = reference.String()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"references": names,
+			"verses":     verses,
+		})
+	})
+
+	r.Get("/v1/bible/lexicon", func(w http.ResponseWriter, r *http.Request) {
+		if service.Passages == nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		params := r.URL.Query()
+		entry, verses, err := service.Passages.Word(params.Get("word"), params["translation"])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entry":  entry,
+			"verses": verses,
+		})
+	})
+
 	r.Post("/v1/completion/stream", func(w http.ResponseWriter, r *http.Request) {
 		upgrader := websocket.Upgrader{}
 		conn, err := upgrader.Upgrade(w, r, nil)
@@ -139,3 +253,31 @@ func serve() {
 	log.Printf("Listening on %s:%d", address, port)
 	http.ListenAndServe(fmt.Sprintf("%s:%d", address, port), r)
 }
+
+// serveMetrics serves the embedding metrics on an admin listener at address,
+// apart from the public API. Unlike expvar.Handler it publishes nothing else
+// about the process, such as its command line.
+func serveMetrics(address string, metrics *embedding.Metrics) {
+	r := chi.NewRouter()
+	r.Get("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"embedding\": %s}\n", metrics.String())
+	})
+	log.Printf("Serving metrics on %s", address)
+	if err := http.ListenAndServe(address, r); err != nil {
+		log.Printf("Metrics listener stopped: %v\n", err)
+	}
+}
+
+// writeError responds with the status code for an error returned by the
+// retrieval service.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch err {
+	case nvoke.ErrInvalidQueryParameters:
+		status = http.StatusBadRequest
+	case nvoke.ErrReferenceNotFound:
+		status = http.StatusNotFound
+	}
+	http.Error(w, http.StatusText(status), status)
+}
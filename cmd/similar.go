@@ -21,7 +21,7 @@ func SearchSimilarEmbeddings(query string) {
 	}
 	// Initialize the OpenAI generator and vectorize the query
 	client := openai.NewClient(OpenAIAPIKey)
-	generator := embedding.NewOpenAIGenerator(client, openai.SmallEmbedding3, 1536)
+	generator := newGenerator(client)
 	// Connect to MongoDB
 	clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
 	mongoClient, err := mongo.Connect(ctx, clientOptions)
@@ -31,7 +31,18 @@ func SearchSimilarEmbeddings(query string) {
 	defer mongoClient.Disconnect(ctx)
 
 	service := nvoke.NewRetrievalService(mongoClient, generator, client)
-	items, err := service.SemanticSearch(ctx, nvoke.Query{Query: query, Persona: persona})
+	service.StrictProvenance = strictProvenance
+	items, err := service.SemanticSearch(ctx, nvoke.Query{
+		Query:        query,
+		Persona:      persona,
+		Translations: translations,
+		Testament:    testament,
+		Books:        books,
+		Strongs:      strongs,
+		WordsOfJesus: wordsOfJesus,
+		Align:        align,
+		Order:        order,
+	})
 	if err != nil {
 		log.Fatalf("Failed to find similar content %v", err)
 	}
@@ -41,10 +52,66 @@ func SearchSimilarEmbeddings(query string) {
 	}
 }
 
+// SearchLocalEmbeddings searches the file written by generate in memory
+// instead of querying MongoDB.
+func SearchLocalEmbeddings(query string) {
+	ctx := context.Background()
+
+	if query == "" {
+		log.Fatalf("Invalid query string \"%s\"\n", query)
+	}
+	documents, index, provenance, err := loadIndex(persona, localInput, embedding.Encoding(encoding), rescore)
+	if err != nil {
+		log.Fatalf("Failed to build %s index: %v", encoding, err)
+	}
+
+	client := openai.NewClient(OpenAIAPIKey)
+	queryEmbedding, err := localQueryGenerator(client, provenance, index.Dimensions()).GenerateEmbedding(ctx, query)
+	if err != nil {
+		log.Fatalf("Failed to generate embedding for the query: %v", err)
+	}
+	matches, err := index.Search(queryEmbedding, limit)
+	if err != nil {
+		log.Fatalf("Failed to search embeddings: %v", err)
+	}
+	fmt.Println("Similar documents")
+	for _, match := range matches {
+		fmt.Printf("%.4f %v\n", match.Score, documents[match.Index])
+	}
+}
+
+// localQueryGenerator embeds queries with the model recorded in the
+// provenance of an embeddings file, truncated to the dimensions of the
+// vectors it holds, so that queries are comparable with them. Files without
+// provenance are searched with the model set by the flags.
+func localQueryGenerator(client *openai.Client, provenance *embedding.Provenance, dimensions int) embedding.Generator {
+	var generator embedding.Generator = newGenerator(client)
+	if provenance != nil && provenance.Model != "" {
+		generator = embedding.NewOpenAIGenerator(client, openai.EmbeddingModel(provenance.Model), provenance.Dimensions)
+	}
+	if dimensions > 0 {
+		generator = embedding.NewTruncatingGenerator(generator, dimensions)
+	}
+	return generator
+}
+
+var local bool
+var localInput string
+var encoding string
+var rescore int
+var order string
+
 var similarCmd = &cobra.Command{
 	Use:   "similar",
 	Short: "similarity search for text",
 	Run: func(cmd *cobra.Command, args []string) {
+		if local {
+			if len(translations) > 0 || testament != "" || len(books) > 0 || len(strongs) > 0 || wordsOfJesus {
+				log.Fatalf("--translation, --testament, --book, --strong and --words-of-jesus are not supported with --local\n")
+			}
+			SearchLocalEmbeddings(query)
+			return
+		}
 		SearchSimilarEmbeddings(query)
 	},
 }
@@ -52,7 +119,18 @@ var similarCmd = &cobra.Command{
 func init() {
 	similarCmd.Flags().StringVarP(&query, "query", "q", "", "Text query to search similar embeddings")
 	similarCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona to use when searching")
+	similarCmd.Flags().StringSliceVarP(&translations, "translation", "t", nil, "Restrict the search to these translations, defaults to all")
+	similarCmd.Flags().StringVar(&testament, "testament", "", "Restrict the search to one testament: OT, NT or DC")
+	similarCmd.Flags().BoolVar(&align, "align", false, "Return every translation of each Tao chapter found")
+	similarCmd.Flags().BoolVar(&wordsOfJesus, "words-of-jesus", false, "Restrict the search to verses spoken by Jesus")
+	similarCmd.Flags().StringSliceVar(&strongs, "strong", nil, "Restrict the search to verses using these Strong's numbers, such as G26")
+	similarCmd.Flags().StringSliceVarP(&books, "book", "b", nil, "Restrict the search to these books or book ranges, such as Romans or Matthew-John")
+	similarCmd.Flags().StringVar(&order, "order", nvoke.OrderRelevance, "Result order: relevance or canonical")
 	similarCmd.Flags().IntVarP(&limit, "limit", "l", 10, "Max similar vectors limit.")
 	similarCmd.Flags().IntVarP(&candidates, "candidates", "c", 200, "Number of candidates to consider.")
+	similarCmd.Flags().BoolVar(&local, "local", false, "Search the generated embeddings file in memory instead of MongoDB")
+	similarCmd.Flags().StringVarP(&localInput, "input", "i", "", "Embeddings file to search with --local")
+	similarCmd.Flags().StringVar(&encoding, "encoding", string(embedding.EncodingFloat32), "Vector encoding for --local: float32, float16, int8 or binary")
+	similarCmd.Flags().IntVar(&rescore, "rescore", 0, "Candidates of an int8 or binary index rescored at half precision with --local")
 	rootCmd.AddCommand(similarCmd)
 }
@@ -2,111 +2,282 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"nvoke/pkg/bible"
-	"nvoke/pkg/tao"
+	"io"
+	"log"
+	"nvoke/nvoke"
+	"nvoke/pkg/embedding"
 	"os"
 
 	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func UploadBibleVersesToMongoDB() {
-	// Open the JSON file
-	file, err := os.Open("texts/bible/nkjv-verses.json")
+var uploadInput string
+var drop bool
+var uploadProgress bool
+
+const uploadBatchSize = 500
+
+// UploadResult counts what an upload did to the active collection.
+// Unchanged documents matched a stored document with the same content.
+type UploadResult struct {
+	Inserted  int64
+	Updated   int64
+	Unchanged int64
+	Failed    int
+}
+
+func (r UploadResult) String() string {
+	return fmt.Sprintf("Inserted %d, updated %d, unchanged %d, failed %d documents", r.Inserted, r.Updated, r.Unchanged, r.Failed)
+}
+
+// Upload upserts the documents generate wrote to path into the collection kb
+// currently serves queries from, in batches as they are read. Documents are
+// keyed by their adapter ID, so uploading the same file twice leaves the
+// collection unchanged. With drop the collection is emptied first, keeping
+// its vector index.
+func Upload(ctx context.Context, client *mongo.Client, kb nvoke.KnowledgeBase, format nvoke.Format, path string, drop bool) (UploadResult, error) {
+	var result UploadResult
+	adapter := format.Adapter()
+	identifier, ok := adapter.(embedding.Identifier[interface{}])
+	if !ok {
+		return result, fmt.Errorf("%s documents have no stable IDs to upsert by", format.Name())
+	}
+	file, err := openEmbeddings(format, path)
 	if err != nil {
-		fmt.Printf("Failed to open verses.json: %v\n", err)
-		return
+		return result, err
 	}
 	defer file.Close()
+	recorded := file.Provenance
 
-	// Read and decode the file content
-	var verses []*bible.Verse
-	err = json.NewDecoder(file).Decode(&verses)
+	catalog := nvoke.NewCatalog(client)
+	active, err := catalog.ActiveCollection(ctx, kb)
 	if err != nil {
-		fmt.Printf("Failed to decode verses: %v\n", err)
-		return
+		return result, fmt.Errorf("failed to read catalog: %v", err)
+	}
+	collection := client.Database(kb.Db).Collection(active)
+	if drop {
+		deleted, err := collection.DeleteMany(ctx, bson.D{})
+		if err != nil {
+			return result, fmt.Errorf("failed to empty %s.%s: %v", kb.Db, active, err)
+		}
+		fmt.Printf("Deleted %d documents from %s.%s\n", deleted.DeletedCount, kb.Db, active)
 	}
 
-	// Connect to MongoDB
-	clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
-	client, err := mongo.Connect(context.Background(), clientOptions)
-	if err != nil {
-		fmt.Printf("Failed to connect to MongoDB: %v\n", err)
-		return
+	var stored embedding.Provenance
+	if recorded != nil {
+		stored = truncatedProvenance(kb, *recorded)
 	}
-	defer client.Disconnect(context.Background())
+	fmt.Printf("Uploading %s to %s.%s\n", path, kb.Db, active)
+	upserter := newUpserter(collection, kb, identifier, countDocuments(path))
+	for {
+		document, err := file.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return upserter.result, err
+		}
+		if recorded != nil {
+			if err := prepareEmbedding(adapter, document, identifier.GetID(document), kb, *recorded); err != nil {
+				return upserter.result, err
+			}
+		}
+		if err := upserter.Add(ctx, document); err != nil {
+			return upserter.result, err
+		}
+	}
+	if result, err = upserter.Finish(ctx); err != nil {
+		return result, err
+	}
+	if recorded == nil {
+		fmt.Printf("No provenance found for %s, queries will not be verified\n", path)
+	} else {
+		recordProvenance(client, kb, stored)
+	}
+	return result, nil
+}
 
-	// Insert documents
-	collection := client.Database("bible").Collection("verses")
-	documents := make([]interface{}, len(verses))
-	for i, verse := range verses {
-		documents[i] = verse
+// upsertDocuments replaces or inserts documents by their stable ID in
+// unordered batches, so that one bad document does not stop the rest.
+func upsertDocuments(ctx context.Context, collection *mongo.Collection, kb nvoke.KnowledgeBase, adapter embedding.Adapter[interface{}], documents []interface{}) (UploadResult, error) {
+	identifier, ok := adapter.(embedding.Identifier[interface{}])
+	if !ok {
+		return UploadResult{}, errors.New("documents have no stable IDs to upsert by")
+	}
+	upserter := newUpserter(collection, kb, identifier, len(documents))
+	for _, document := range documents {
+		if err := upserter.Add(ctx, document); err != nil {
+			return upserter.result, err
+		}
 	}
-	_, err = collection.InsertMany(context.Background(), documents)
+	return upserter.Finish(ctx)
+}
+
+// upserter replaces or inserts documents by their stable ID as they are
+// added, writing them in unordered batches of uploadBatchSize.
+type upserter struct {
+	collection *mongo.Collection
+	kb         nvoke.KnowledgeBase
+	identifier embedding.Identifier[interface{}]
+	bar        *embedding.ProgressBar
+	total      int
+	models     []mongo.WriteModel
+	added      int
+	result     UploadResult
+	firstErr   error
+}
+
+// newUpserter writes to collection, reporting progress against an expected
+// total number of documents.
+func newUpserter(collection *mongo.Collection, kb nvoke.KnowledgeBase, identifier embedding.Identifier[interface{}], total int) *upserter {
+	u := &upserter{collection: collection, kb: kb, identifier: identifier, total: total}
+	if uploadProgress {
+		u.bar = embedding.NewProgressBar(os.Stderr)
+	}
+	return u
+}
+
+// Add queues document, writing the batch once it is full.
+func (u *upserter) Add(ctx context.Context, document interface{}) error {
+	id := u.identifier.GetID(document)
+	replacement, err := storedDocument(u.kb, id, document)
 	if err != nil {
-		fmt.Printf("Failed to insert verses into MongoDB: %v\n", err)
-		return
+		return err
+	}
+	u.models = append(u.models, mongo.NewReplaceOneModel().
+		SetFilter(bson.D{{Key: "_id", Value: id}}).
+		SetReplacement(replacement).
+		SetUpsert(true))
+	u.added++
+	if len(u.models) < uploadBatchSize {
+		return nil
 	}
+	return u.flush(ctx)
+}
 
-	fmt.Println("Verses uploaded to MongoDB successfully")
+func (u *upserter) flush(ctx context.Context) error {
+	if len(u.models) == 0 {
+		return nil
+	}
+	written, err := u.collection.BulkWrite(ctx, u.models, options.BulkWrite().SetOrdered(false))
+	u.models = nil
+	if written != nil {
+		u.result.Inserted += written.UpsertedCount
+		u.result.Updated += written.ModifiedCount
+		u.result.Unchanged += written.MatchedCount - written.ModifiedCount
+	}
+	var bulkErr mongo.BulkWriteException
+	switch {
+	case errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0:
+		// Unordered writes carry on past failed documents, so count
+		// them and keep going with the next batch.
+		u.result.Failed += len(bulkErr.WriteErrors)
+		if u.firstErr == nil {
+			u.firstErr = bulkErr.WriteErrors[0]
+		}
+	case err != nil:
+		return fmt.Errorf("failed to upload documents: %v", err)
+	}
+	if u.bar != nil {
+		u.bar.BatchCompleted(embedding.BatchEvent{Completed: u.added, Total: max(u.total, u.added)})
+	}
+	return nil
 }
 
-func UploadTaoChaptersToMongoDB() {
-	// Open the JSON file
-	// file, err := os.Open("texts/tao/linn/tao.json")
-	// if err != nil {
-	// 	fmt.Printf("Failed to open verses.json: %v\n", err)
-	// 	return
-	// }
-	// defer file.Close()
-
-	// Connect to MongoDB
-	clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
-	client, err := mongo.Connect(context.Background(), clientOptions)
+// Finish writes the last batch and returns what the upload did.
+func (u *upserter) Finish(ctx context.Context) (UploadResult, error) {
+	err := u.flush(ctx)
+	if u.bar != nil {
+		u.bar.Finish()
+	}
 	if err != nil {
-		fmt.Printf("Failed to connect to MongoDB: %v\n", err)
-		return
+		return u.result, err
+	}
+	if u.result.Failed > 0 {
+		return u.result, fmt.Errorf("%d of %d documents failed to upload, first error: %v", u.result.Failed, u.added, u.firstErr)
 	}
-	defer client.Disconnect(context.Background())
-
-	// Insert documents
-	collection := client.Database("tao").Collection("chapters")
-
-	document := tao.Chapter{
-		Chapter: 1,
-		Text: `The Tao that can be spoken is not the eternal Tao
-		The name that can be named is not the eternal name
-		The nameless is the origin of Heaven and Earth
-		The named is the mother of myriad things
-		Thus, constantly without desire, one observes its essence
-		Constantly with desire, one observes its manifestations
-		These two emerge together but differ in name
-		The unity is said to be the mystery
-		Mystery of mysteries, the door to all wonders`,
-		Embedding: nil,
-	}
-	_, err = collection.InsertOne(context.Background(), document)
+	return u.result, nil
+}
+
+// storedDocument encodes document for kb with id as its _id, so the stored
+// document can be found and replaced by the ID its adapter gives it, and
+// with its content hash so that ingest can tell when it changes.
+func storedDocument(kb nvoke.KnowledgeBase, id string, document interface{}) (bson.D, error) {
+	hash, err := nvoke.ContentHash(kb, document)
 	if err != nil {
-		fmt.Printf("Failed to insert chapters into MongoDB: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to hash %s: %v", id, err)
+	}
+	encoded, err := bson.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", id, err)
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %v", id, err)
+	}
+	stored := make(bson.D, 0, len(fields)+2)
+	stored = append(stored, bson.E{Key: "_id", Value: id})
+	for _, field := range fields {
+		if field.Key != "_id" && field.Key != nvoke.ContentHashField {
+			stored = append(stored, field)
+		}
 	}
+	return append(stored, bson.E{Key: nvoke.ContentHashField, Value: hash}), nil
+}
 
-	fmt.Println("Chapters uploaded to MongoDB successfully")
+// recordProvenance stores the provenance of uploaded embeddings in the catalog so
+// queries can be checked against the model that embedded the documents.
+func recordProvenance(client *mongo.Client, kb nvoke.KnowledgeBase, provenance embedding.Provenance) {
+	if err := nvoke.NewCatalog(client).SetProvenance(context.Background(), kb, provenance); err != nil {
+		fmt.Printf("Failed to record provenance: %v\n", err)
+		return
+	}
+	fmt.Printf("Recorded embedding provenance %s for %s.%s\n", provenance, kb.Db, kb.Collection)
 }
 
 var uploadCmd = &cobra.Command{
 	Use:   "upload",
 	Short: "Upload documents to MongoDB",
 	Run: func(cmd *cobra.Command, args []string) {
-		// Assuming you have a function `UploadVersesToMongoDB` implemented
-		UploadBibleVersesToMongoDB()
-		UploadTaoChaptersToMongoDB()
+		ctx := context.Background()
+		kb, ok := nvoke.KnowledgeBases[persona]
+		if !ok {
+			log.Fatalf("Invalid persona %q\n", persona)
+		}
+		format, err := resolveFormat()
+		if err != nil {
+			log.Fatalf("Error uploading %s: %v\n", persona, err)
+		}
+		path := uploadInput
+		if path == "" {
+			path = embeddingsPath(format)
+		}
+
+		clientOptions := options.Client().ApplyURI(MongoDBConnectionString)
+		client, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer client.Disconnect(ctx)
+
+		result, err := Upload(ctx, client, kb, format, path, drop)
+		fmt.Println(result)
+		if err != nil {
+			log.Fatalf("Error uploading %s: %v\n", persona, err)
+		}
 	},
 }
 
 func init() {
+	uploadCmd.Flags().StringVarP(&persona, "persona", "p", "", "The persona whose knowledge base is uploaded")
+	uploadCmd.Flags().StringVarP(&formatName, "format", "f", "", "Corpus format of the input, defaulting to the persona's format")
+	uploadCmd.Flags().StringVarP(&uploadInput, "input", "i", "", "Embeddings file produced by generate, defaulting to the format's output")
+	uploadCmd.Flags().BoolVar(&drop, "drop", false, "Delete every document in the active collection before uploading")
+	uploadCmd.Flags().BoolVar(&uploadProgress, "progress", true, "Show a progress bar")
 	rootCmd.AddCommand(uploadCmd)
 }
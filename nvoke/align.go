@@ -0,0 +1,99 @@
+package nvoke
+
+import (
+	"context"
+	"log"
+	"nvoke/pkg/tao"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Aligned returns a chapter of the Tao Te Ching in each of translations, or
+// in every translation loaded into the tao knowledge base, ordered by
+// translation.
+func (rs *RetrievalService) Aligned(ctx context.Context, chapter int, translations []string) ([]*tao.Chapter, error) {
+	if chapter <= 0 {
+		return nil, ErrInvalidQueryParameters
+	}
+	chapters, err := rs.alignedChapters(ctx, []int{chapter}, translations)
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		return nil, ErrReferenceNotFound
+	}
+	return chapters, nil
+}
+
+// alignedChapters finds the given chapter numbers in each of translations,
+// ordered by chapter and then translation.
+func (rs *RetrievalService) alignedChapters(ctx context.Context, numbers []int, translations []string) ([]*tao.Chapter, error) {
+	kb, ok := rs.KnowledgeBases["tao"]
+	if !ok {
+		return nil, ErrInvalidQueryParameters
+	}
+	name, err := rs.Catalog.ActiveCollection(ctx, kb)
+	if err != nil {
+		log.Printf("Failed to read catalog for %s.%s: %v\n", kb.Db, kb.Collection, err)
+		name = kb.Collection
+	}
+
+	filter := bson.D{{Key: "chapter", Value: bson.D{{Key: "$in", Value: numbers}}}}
+	if len(translations) > 0 {
+		upper := make([]string, len(translations))
+		for i, translation := range translations {
+			upper[i] = strings.ToUpper(translation)
+		}
+		filter = append(filter, bson.E{Key: "translation", Value: bson.D{{Key: "$in", Value: upper}}})
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "chapter", Value: 1}, {Key: "translation", Value: 1}}).
+		SetProjection(bson.D{{Key: kb.Path, Value: 0}})
+	cursor, err := rs.Mongodb.Database(kb.Db).Collection(name).Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Failed to find aligned chapters: %v\n", err)
+		return nil, ErrSimilaritySearchFailed
+	}
+	chapters := make([]*tao.Chapter, 0)
+	if err := cursor.All(ctx, &chapters); err != nil {
+		log.Printf("Failed to decode aligned chapters: %v\n", err)
+		return nil, ErrSimilaritySearchFailed
+	}
+	return chapters, nil
+}
+
+// align replaces each chapter found by a search with its renderings in every
+// translation, keeping the chapters in search order so the persona can
+// compare translations of the most relevant chapters first.
+func (rs *RetrievalService) align(ctx context.Context, query Query, results []interface{}) ([]interface{}, error) {
+	rank := make(map[int]int)
+	numbers := make([]int, 0)
+	for _, result := range results {
+		chapter, ok := result.(*tao.Chapter)
+		if !ok {
+			return results, nil
+		}
+		if _, ok := rank[chapter.Chapter]; !ok {
+			rank[chapter.Chapter] = len(numbers)
+			numbers = append(numbers, chapter.Chapter)
+		}
+	}
+	if len(numbers) == 0 {
+		return results, nil
+	}
+	chapters, err := rs.alignedChapters(ctx, numbers, query.Translations)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return rank[chapters[i].Chapter] < rank[chapters[j].Chapter]
+	})
+	aligned := make([]interface{}, len(chapters))
+	for i, chapter := range chapters {
+		aligned[i] = chapter
+	}
+	return aligned, nil
+}
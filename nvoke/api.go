@@ -3,4 +3,30 @@ package nvoke
 type Query struct {
 	Query   string `json:"query"`
 	Persona string `json:"persona"`
+	// Translations restricts the search to the named translations. An empty
+	// list searches every translation in the knowledge base.
+	Translations []string `json:"translations,omitempty"`
+	// Testament restricts the search to the books of one testament: OT, NT
+	// or DC for the deuterocanonical books.
+	Testament string `json:"testament,omitempty"`
+	// Books restricts the search to the named books or book ranges, such as
+	// "Romans" or "Matthew-John".
+	Books []string `json:"books,omitempty"`
+	// Strongs restricts the search to verses using any of these Strong's
+	// numbers, such as G26.
+	Strongs []string `json:"strongs,omitempty"`
+	// WordsOfJesus restricts the search to verses spoken by Jesus.
+	WordsOfJesus bool `json:"wordsOfJesus,omitempty"`
+	// Align returns every translation of each Tao chapter found, so that
+	// their renderings can be compared.
+	Align bool `json:"align,omitempty"`
+	// Order sorts the results. OrderCanonical puts verses in Bible order
+	// instead of by similarity.
+	Order string `json:"order,omitempty"`
 }
+
+// Result orders accepted by Query.Order.
+const (
+	OrderRelevance = "relevance"
+	OrderCanonical = "canonical"
+)
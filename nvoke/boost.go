@@ -0,0 +1,65 @@
+package nvoke
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scoreField holds the vector search score of each result when the knowledge
+// base boosts some documents.
+const scoreField = "_score"
+
+// boostOversampling is how many times the limit of results is fetched from
+// the vector search when the knowledge base boosts some documents, so that
+// boosted documents ranked just outside the limit can move into it.
+const boostOversampling = 3
+
+// searchLimit returns the number of results to fetch from the vector search
+// of kb. It never exceeds the number of candidates searched.
+func searchLimit(kb KnowledgeBase) int {
+	if len(kb.Boosts) == 0 {
+		return kb.Limit
+	}
+	return max(kb.Limit, min(kb.Limit*boostOversampling, kb.Candidates))
+}
+
+// boostedScore returns the vector search score of a result multiplied by the
+// boost of each of the knowledge base's boolean fields that is true in it.
+func boostedScore(kb KnowledgeBase, document bson.Raw) float64 {
+	score, ok := document.Lookup(scoreField).DoubleOK()
+	if !ok {
+		return 0
+	}
+	for field, factor := range kb.Boosts {
+		if value, ok := document.Lookup(field).BooleanOK(); ok && value {
+			score *= factor
+		}
+	}
+	return score
+}
+
+// sortByScore orders results by descending score, keeping the search order
+// of results with equal scores.
+func sortByScore(results []interface{}, scores []float64) {
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	sorted := make([]interface{}, len(results))
+	for i, index := range order {
+		sorted[i] = results[index]
+	}
+	copy(results, sorted)
+}
+
+// rankBoosted orders results by their boosted scores and keeps the limit of
+// results of kb.
+func rankBoosted(kb KnowledgeBase, results []interface{}, scores []float64) []interface{} {
+	sortByScore(results, scores)
+	if len(results) > kb.Limit {
+		results = results[:kb.Limit]
+	}
+	return results
+}
@@ -0,0 +1,35 @@
+package nvoke
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSearchLimit(t *testing.T) {
+	assert.Equal(t, 20, searchLimit(KnowledgeBase{Limit: 20, Candidates: 200}))
+	assert.Equal(t, 60, searchLimit(KnowledgeBase{Limit: 20, Candidates: 200, Boosts: map[string]float64{"wordsofjesus": 1.05}}))
+	assert.Equal(t, 30, searchLimit(KnowledgeBase{Limit: 20, Candidates: 30, Boosts: map[string]float64{"wordsofjesus": 1.05}}))
+	assert.Equal(t, 20, searchLimit(KnowledgeBase{Limit: 20, Candidates: 10, Boosts: map[string]float64{"wordsofjesus": 1.05}}))
+}
+
+func TestRankBoosted(t *testing.T) {
+	kb := KnowledgeBase{Limit: 2, Boosts: map[string]float64{"wordsofjesus": 1.05}}
+	found := []bson.M{
+		{"id": "JHN.3.16", scoreField: 0.90, "wordsofjesus": false},
+		{"id": "JHN.3.17", scoreField: 0.88, "wordsofjesus": false},
+		{"id": "JHN.11.35", scoreField: 0.87, "wordsofjesus": true},
+		{"id": "JHN.14.6", scoreField: 0.80, "wordsofjesus": true},
+	}
+	results := make([]interface{}, 0, len(found))
+	scores := make([]float64, 0, len(found))
+	for _, document := range found {
+		raw, err := bson.Marshal(document)
+		assert.NoError(t, err)
+		results = append(results, document["id"])
+		scores = append(scores, boostedScore(kb, raw))
+	}
+
+	assert.Equal(t, []interface{}{"JHN.11.35", "JHN.3.16"}, rankBoosted(kb, results, scores))
+}
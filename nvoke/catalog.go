@@ -0,0 +1,191 @@
+package nvoke
+
+import (
+	"context"
+	"errors"
+	"nvoke/pkg/embedding"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CatalogCollection is the collection in each KnowledgeBase database that
+// holds metadata about the stored documents.
+const CatalogCollection = "catalog"
+
+var ErrNotCataloged = errors.New("knowledge base has no catalog entry")
+var ErrVersionExists = errors.New("collection version already exists")
+var ErrUnknownVersion = errors.New("unknown collection version")
+var ErrNoPreviousVersion = errors.New("no previous collection version to roll back to")
+var ErrConcurrentUpdate = errors.New("catalog entry was changed concurrently")
+
+// CollectionVersion describes one versioned copy of a KnowledgeBase collection.
+type CollectionVersion struct {
+	Name       string               `bson:"name"`
+	Provenance embedding.Provenance `bson:"provenance"`
+	Count      int64                `bson:"count"`
+	CreatedAt  time.Time            `bson:"created_at"`
+}
+
+// CatalogEntry records how the documents of a KnowledgeBase were embedded and
+// which versioned collection currently serves queries. Entries written before
+// versioning have no Active collection and are served from Collection itself.
+type CatalogEntry struct {
+	Collection string               `bson:"_id"`
+	Provenance embedding.Provenance `bson:"provenance"`
+	Active     string               `bson:"active,omitempty"`
+	Previous   string               `bson:"previous,omitempty"`
+	Versions   []CollectionVersion  `bson:"versions,omitempty"`
+}
+
+// ActiveCollection is the name of the collection that queries should use.
+func (e *CatalogEntry) ActiveCollection() string {
+	if e.Active == "" {
+		return e.Collection
+	}
+	return e.Active
+}
+
+// Version returns the version with the given collection name.
+func (e *CatalogEntry) Version(name string) (CollectionVersion, bool) {
+	for _, version := range e.Versions {
+		if version.Name == name {
+			return version, true
+		}
+	}
+	return CollectionVersion{}, false
+}
+
+// Catalog reads and writes KnowledgeBase metadata stored alongside the documents.
+type Catalog struct {
+	mongodb *mongo.Client
+}
+
+func NewCatalog(mongodb *mongo.Client) *Catalog {
+	return &Catalog{mongodb: mongodb}
+}
+
+func (c *Catalog) collection(kb KnowledgeBase) *mongo.Collection {
+	return c.mongodb.Database(kb.Db).Collection(CatalogCollection)
+}
+
+// Get returns the catalog entry for kb or ErrNotCataloged if there is none.
+func (c *Catalog) Get(ctx context.Context, kb KnowledgeBase) (*CatalogEntry, error) {
+	var entry CatalogEntry
+	err := c.collection(kb).FindOne(ctx, bson.D{{Key: "_id", Value: kb.Collection}}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotCataloged
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// SetProvenance records the provenance of the embeddings stored for kb.
+func (c *Catalog) SetProvenance(ctx context.Context, kb KnowledgeBase, provenance embedding.Provenance) error {
+	_, err := c.collection(kb).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: kb.Collection}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "provenance", Value: provenance}}}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// ActiveCollection returns the collection currently serving kb, falling back
+// to kb.Collection when the knowledge base has never been reindexed.
+func (c *Catalog) ActiveCollection(ctx context.Context, kb KnowledgeBase) (string, error) {
+	entry, err := c.Get(ctx, kb)
+	if err == ErrNotCataloged {
+		return kb.Collection, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return entry.ActiveCollection(), nil
+}
+
+// AddVersion registers a new collection version without activating it.
+func (c *Catalog) AddVersion(ctx context.Context, kb KnowledgeBase, version CollectionVersion) error {
+	entry, err := c.Get(ctx, kb)
+	if err != nil && err != ErrNotCataloged {
+		return err
+	}
+	if entry != nil {
+		if _, ok := entry.Version(version.Name); ok {
+			return ErrVersionExists
+		}
+	}
+	_, err = c.collection(kb).UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: kb.Collection}},
+		bson.D{{Key: "$push", Value: bson.D{{Key: "versions", Value: version}}}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Activate points kb at the named version. The switch is a single document
+// update conditioned on the previously active collection, so concurrent
+// readers always see either the old or the new version.
+func (c *Catalog) Activate(ctx context.Context, kb KnowledgeBase, name string) error {
+	entry, err := c.Get(ctx, kb)
+	if err != nil {
+		return err
+	}
+	version, ok := entry.Version(name)
+	if !ok {
+		return ErrUnknownVersion
+	}
+	return c.swap(ctx, kb, entry.Active, version.Name, entry.ActiveCollection(), version.Provenance)
+}
+
+// Rollback reactivates the previously active collection.
+func (c *Catalog) Rollback(ctx context.Context, kb KnowledgeBase) error {
+	entry, err := c.Get(ctx, kb)
+	if err != nil {
+		return err
+	}
+	previous, provenance, err := entry.rollback()
+	if err != nil {
+		return err
+	}
+	return c.swap(ctx, kb, entry.Active, previous, entry.ActiveCollection(), provenance)
+}
+
+// rollback returns the collection a rollback reactivates and the provenance
+// of its embeddings. Collections served before versioning, such as the
+// original collection of a knowledge base, have no version record, so their
+// provenance is unknown rather than that of the collection being replaced.
+func (e *CatalogEntry) rollback() (string, embedding.Provenance, error) {
+	if e.Previous == "" {
+		return "", embedding.Provenance{}, ErrNoPreviousVersion
+	}
+	version, ok := e.Version(e.Previous)
+	if !ok {
+		return e.Previous, embedding.Provenance{}, nil
+	}
+	return e.Previous, version.Provenance, nil
+}
+
+func (c *Catalog) swap(ctx context.Context, kb KnowledgeBase, expected, active, previous string, provenance embedding.Provenance) error {
+	filter := bson.D{{Key: "_id", Value: kb.Collection}}
+	if expected == "" {
+		filter = append(filter, bson.E{Key: "active", Value: bson.D{{Key: "$exists", Value: false}}})
+	} else {
+		filter = append(filter, bson.E{Key: "active", Value: expected})
+	}
+	result, err := c.collection(kb).UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: bson.D{
+		{Key: "active", Value: active},
+		{Key: "previous", Value: previous},
+		{Key: "provenance", Value: provenance},
+	}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrConcurrentUpdate
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package nvoke
+
+import (
+	"nvoke/pkg/embedding"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogEntry_Rollback(t *testing.T) {
+	small := embedding.Provenance{Model: "text-embedding-3-small", Dimensions: 1536}
+	large := embedding.Provenance{Model: "text-embedding-3-large", Dimensions: 512}
+
+	entry := &CatalogEntry{
+		Collection: "verses",
+		Provenance: large,
+		Active:     "verses_v2",
+		Previous:   "verses_v1",
+		Versions: []CollectionVersion{
+			{Name: "verses_v1", Provenance: small},
+			{Name: "verses_v2", Provenance: large},
+		},
+	}
+	previous, provenance, err := entry.rollback()
+	assert.NoError(t, err)
+	assert.Equal(t, "verses_v1", previous)
+	assert.Equal(t, small, provenance)
+
+	// The original collection predates versioning, so the provenance of the
+	// version being replaced must not be carried over to it.
+	entry = &CatalogEntry{
+		Collection: "verses",
+		Provenance: large,
+		Active:     "verses_v1",
+		Previous:   "verses",
+		Versions:   []CollectionVersion{{Name: "verses_v1", Provenance: large}},
+	}
+	previous, provenance, err = entry.rollback()
+	assert.NoError(t, err)
+	assert.Equal(t, "verses", previous)
+	assert.Equal(t, embedding.Provenance{}, provenance)
+
+	_, _, err = (&CatalogEntry{Collection: "verses"}).rollback()
+	assert.ErrorIs(t, err, ErrNoPreviousVersion)
+}
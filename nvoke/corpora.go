@@ -0,0 +1,126 @@
+package nvoke
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/document"
+	"os"
+	"path/filepath"
+)
+
+// DefaultCorporaPath is the configuration file read by LoadCorpora when no
+// other path is given.
+const DefaultCorporaPath = "corpora.json"
+
+// Corpus configures a plain text, Markdown or EPUB corpus. Registering it adds a
+// format and a knowledge base under its name, so it can be generated,
+// reindexed and searched like the built-in corpora.
+type Corpus struct {
+	Name string `json:"name"`
+	// Input is the source file or directory. It defaults to texts/<name>/.
+	Input string `json:"input,omitempty"`
+	// Output is where generate writes embeddings. It defaults to
+	// texts/<name>/documents.jsonl.
+	Output         string `json:"output,omitempty"`
+	Syntax         string `json:"syntax,omitempty"`
+	SectionPattern string `json:"section_pattern,omitempty"`
+	ChunkTokens    int    `json:"chunk_tokens,omitempty"`
+	ChunkOverlap   int    `json:"chunk_overlap,omitempty"`
+	// Db and Collection default to the corpus name and "documents".
+	Db         string `json:"db,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	Index      string `json:"index,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Candidates int    `json:"candidates,omitempty"`
+	Dimensions int    `json:"dimensions,omitempty"`
+	// Prompt is the system prompt of the corpus persona.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// LoadCorpora registers the corpora listed in the JSON file at path. A
+// missing file registers nothing.
+func LoadCorpora(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var corpora []Corpus
+	if err := json.Unmarshal(data, &corpora); err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	for _, corpus := range corpora {
+		if err := RegisterCorpus(corpus); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// RegisterCorpus adds the format and knowledge base of corpus.
+func RegisterCorpus(corpus Corpus) error {
+	if corpus.Name == "" {
+		return errors.New("corpus has no name")
+	}
+	if _, ok := KnowledgeBases[corpus.Name]; ok {
+		return fmt.Errorf("corpus %q is already registered", corpus.Name)
+	}
+	if _, ok := formats[corpus.Name]; ok {
+		return fmt.Errorf("corpus %q is already registered", corpus.Name)
+	}
+	switch corpus.Syntax {
+	case "", document.SyntaxMarkdown, document.SyntaxText, document.SyntaxEPUB:
+	default:
+		return fmt.Errorf("corpus %q: unrecognized source syntax %q", corpus.Name, corpus.Syntax)
+	}
+	if corpus.Input == "" {
+		corpus.Input = filepath.Join("texts", corpus.Name) + "/"
+	}
+	if corpus.Output == "" {
+		corpus.Output = filepath.Join("texts", corpus.Name, "documents.jsonl")
+	}
+
+	KnowledgeBases[corpus.Name] = KnowledgeBase{
+		Index:      valueOr(corpus.Index, "embedding"),
+		Path:       "embedding",
+		Db:         valueOr(corpus.Db, corpus.Name),
+		Collection: valueOr(corpus.Collection, "documents"),
+		Limit:      numberOr(corpus.Limit, 20),
+		Candidates: numberOr(corpus.Candidates, 200),
+		Dimensions: corpus.Dimensions,
+		persona:    document.NewPersona(corpus.Prompt),
+		document:   func() interface{} { return &document.Document{} },
+	}
+	RegisterFormat(NewFormat(
+		corpus.Name, corpus.Name, corpus.Input, corpus.Output,
+		func(mode diagnostic.Mode) Parser[document.Document] {
+			return &document.Parser{
+				Mode:           mode,
+				Syntax:         corpus.Syntax,
+				SectionPattern: corpus.SectionPattern,
+				ChunkTokens:    corpus.ChunkTokens,
+				ChunkOverlap:   corpus.ChunkOverlap,
+			}
+		},
+		func() DocumentAdapter[*document.Document] { return &document.EmbeddingAdapter{} },
+	))
+	return nil
+}
+
+func valueOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func numberOr(value int, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package nvoke
+
+import (
+	"log"
+	"nvoke/pkg/bible"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Filterable reports whether field is indexed for filtering in kb.
+func (kb *KnowledgeBase) Filterable(field string) bool {
+	for _, filter := range kb.Filters {
+		if filter == field {
+			return true
+		}
+	}
+	return false
+}
+
+// searchFilter builds the $vectorSearch pre-filter that restricts a query to
+// the documents it selects. Filtering on a field the knowledge base does not
+// index is rejected because Atlas would fail the whole search.
+func searchFilter(kb KnowledgeBase, query Query) (bson.D, error) {
+	filter := bson.D{}
+	if len(query.Translations) > 0 {
+		if !kb.Filterable("translation") {
+			log.Printf("persona %v does not support translations\n", query.Persona)
+			return nil, ErrInvalidQueryParameters
+		}
+		translations := make([]string, len(query.Translations))
+		for i, translation := range query.Translations {
+			translations[i] = strings.ToUpper(translation)
+		}
+		filter = append(filter, bson.E{Key: "translation", Value: bson.D{{Key: "$in", Value: translations}}})
+	}
+	if query.Testament != "" || len(query.Books) > 0 {
+		if !kb.Filterable("bookid") {
+			log.Printf("persona %v does not support book filters\n", query.Persona)
+			return nil, ErrInvalidQueryParameters
+		}
+		books, err := filterBooks(query.Testament, query.Books)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, bson.E{Key: "bookid", Value: bson.D{{Key: "$in", Value: books}}})
+	}
+	if len(query.Strongs) > 0 {
+		if !kb.Filterable("strongs") {
+			log.Printf("persona %v does not support Strong's numbers\n", query.Persona)
+			return nil, ErrInvalidQueryParameters
+		}
+		numbers := make([]string, len(query.Strongs))
+		for i, strong := range query.Strongs {
+			number, ok := bible.NormalizeStrong(strong)
+			if !ok {
+				log.Printf("invalid Strong's number %v\n", strong)
+				return nil, ErrInvalidQueryParameters
+			}
+			numbers[i] = number
+		}
+		filter = append(filter, bson.E{Key: "strongs", Value: bson.D{{Key: "$in", Value: numbers}}})
+	}
+	if query.WordsOfJesus {
+		if !kb.Filterable("wordsofjesus") {
+			log.Printf("persona %v does not support words of Jesus\n", query.Persona)
+			return nil, ErrInvalidQueryParameters
+		}
+		filter = append(filter, bson.E{Key: "wordsofjesus", Value: true})
+	}
+	switch query.Order {
+	case "", OrderRelevance, OrderCanonical:
+	default:
+		log.Printf("invalid order %v\n", query.Order)
+		return nil, ErrInvalidQueryParameters
+	}
+	return filter, nil
+}
+
+// filterBooks returns the codes of the books in testament that are also
+// named by names, in canonical order. An empty testament or names list does
+// not restrict the books.
+func filterBooks(testament string, names []string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, name := range names {
+		books, err := resolveBooks(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			selected[book.ID] = true
+		}
+	}
+	testament = strings.ToUpper(testament)
+	switch testament {
+	case "", bible.OldTestament, bible.NewTestament, bible.Deuterocanon:
+	default:
+		log.Printf("invalid testament %v\n", testament)
+		return nil, ErrInvalidQueryParameters
+	}
+	ids := make([]string, 0)
+	for _, book := range bible.Books {
+		if (testament == "" || book.Testament == testament) && (len(names) == 0 || selected[book.ID]) {
+			ids = append(ids, book.ID)
+		}
+	}
+	if len(ids) == 0 {
+		log.Printf("no books in testament %v match %v\n", testament, names)
+		return nil, ErrInvalidQueryParameters
+	}
+	return ids, nil
+}
+
+// resolveBooks resolves a book name or a range of books written as two names
+// joined by a hyphen, such as "Gen-Deut".
+func resolveBooks(name string) ([]bible.BookInfo, error) {
+	if book, ok := bible.ResolveBook(name); ok {
+		return []bible.BookInfo{book}, nil
+	}
+	if first, last, ok := strings.Cut(name, "-"); ok {
+		if books, ok := bible.BookRange(first, last); ok {
+			return books, nil
+		}
+	}
+	log.Printf("invalid book %v\n", name)
+	return nil, ErrInvalidQueryParameters
+}
+
+// sortCanonical puts the results in Bible order when they are all verses.
+func sortCanonical(results []interface{}) {
+	verses := make([]*bible.Verse, len(results))
+	for i, result := range results {
+		verse, ok := result.(*bible.Verse)
+		if !ok {
+			return
+		}
+		verses[i] = verse
+	}
+	bible.SortVerses(verses)
+	for i, verse := range verses {
+		results[i] = verse
+	}
+}
@@ -0,0 +1,61 @@
+package nvoke
+
+import (
+	"nvoke/pkg/bible"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterBooks(t *testing.T) {
+	tests := []struct {
+		testament string
+		names     []string
+		expected  []string
+	}{
+		{"", []string{"Romans"}, []string{"ROM"}},
+		{"", []string{"John", "Gen"}, []string{"GEN", "JHN"}},
+		{"", []string{"Matthew-John"}, []string{"MAT", "MRK", "LUK", "JHN"}},
+		{"nt", []string{"Psalms", "Jude", "Revelation"}, []string{"JUD", "REV"}},
+		{"DC", []string{"Tobit", "Acts"}, []string{"TOB"}},
+	}
+	for _, test := range tests {
+		books, err := filterBooks(test.testament, test.names)
+		assert.NoError(t, err, "%s %v", test.testament, test.names)
+		assert.Equal(t, test.expected, books, "%s %v", test.testament, test.names)
+	}
+
+	books, err := filterBooks("NT", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, books, 27) {
+		assert.Equal(t, "MAT", books[0])
+		assert.Equal(t, "REV", books[26])
+	}
+
+	for _, test := range []struct {
+		testament string
+		names     []string
+	}{
+		{"XT", nil},
+		{"", []string{"Hezekiah"}},
+		{"", []string{"John-Hezekiah"}},
+		{"OT", []string{"Romans"}},
+	} {
+		_, err := filterBooks(test.testament, test.names)
+		assert.ErrorIs(t, err, ErrInvalidQueryParameters, "%s %v", test.testament, test.names)
+	}
+}
+
+func TestSortCanonical(t *testing.T) {
+	john := &bible.Verse{Translation: "KJV", BookID: "JHN", Chapter: 3, Verse: 16}
+	genesis := &bible.Verse{Translation: "KJV", BookID: "GEN", Chapter: 1, Verse: 1}
+	earlier := &bible.Verse{Translation: "KJV", BookID: "JHN", Chapter: 1, Verse: 1}
+	results := []interface{}{john, genesis, earlier}
+	sortCanonical(results)
+	assert.Equal(t, []interface{}{genesis, earlier, john}, results)
+
+	// Results that are not all verses keep their relevance order.
+	mixed := []interface{}{john, "entry", genesis}
+	sortCanonical(mixed)
+	assert.Equal(t, []interface{}{john, "entry", genesis}, mixed)
+}
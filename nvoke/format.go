@@ -0,0 +1,221 @@
+package nvoke
+
+import (
+	"errors"
+	"fmt"
+	"nvoke/pkg/bible"
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/embedding"
+	"nvoke/pkg/tao"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownFormat = errors.New("unknown corpus format")
+
+// Format describes a corpus format: how to parse its source texts, how to
+// embed the resulting documents and where generate reads and writes them by
+// default. Documents are handled as interface{} so commands can work with any
+// registered format without knowing its document type.
+type Format interface {
+	Name() string
+	Persona() string
+	Input() string
+	Output() string
+	// LegacyOutput is the JSON array of documents written for the format's
+	// persona before embeddings files existed, or empty when there is none.
+	LegacyOutput() string
+	Parse(location string, mode diagnostic.Mode) ([]interface{}, diagnostic.List, error)
+	// Version is the version of the format's parser, or zero when its parser
+	// is not Versioned.
+	Version() int
+	// NewDocument returns a pointer to decode one document of the format into.
+	NewDocument() interface{}
+	// Lint checks parsed documents with the format's Linter, returning no
+	// diagnostics when its parser has none.
+	Lint(documents []interface{}) diagnostic.List
+	Adapter() embedding.Adapter[interface{}]
+}
+
+// DocumentAdapter is the adapter a format needs so that generated embeddings
+// can be checkpointed and read back.
+type DocumentAdapter[T any] interface {
+	embedding.Adapter[T]
+	embedding.Identifier[T]
+	embedding.EmbeddingReader[T]
+}
+
+type format[T any] struct {
+	name    string
+	persona string
+	input   string
+	output  string
+	parser  func(mode diagnostic.Mode) Parser[T]
+	adapter func() DocumentAdapter[*T]
+}
+
+// NewFormat describes a format whose parser produces *T documents.
+func NewFormat[T any](name string, persona string, input string, output string, parser func(mode diagnostic.Mode) Parser[T], adapter func() DocumentAdapter[*T]) Format {
+	return &format[T]{
+		name:    name,
+		persona: persona,
+		input:   input,
+		output:  output,
+		parser:  parser,
+		adapter: adapter,
+	}
+}
+
+func (f *format[T]) Name() string    { return f.name }
+func (f *format[T]) Persona() string { return f.persona }
+func (f *format[T]) Input() string   { return f.input }
+func (f *format[T]) Output() string  { return f.output }
+
+// legacyOutputs are the files each persona's documents were written to
+// before formats and embeddings files existed.
+var legacyOutputs = map[string]string{
+	"bible": "texts/bible/nkjv-verses.json",
+	"tao":   "texts/tao/linn/chapters.json",
+}
+
+func (f *format[T]) LegacyOutput() string { return legacyOutputs[f.persona] }
+
+func (f *format[T]) Parse(location string, mode diagnostic.Mode) ([]interface{}, diagnostic.List, error) {
+	parser := f.parser(mode)
+	documents, err := parser.Parse(location)
+	return erase(documents), parser.Diagnostics(), err
+}
+
+func (f *format[T]) Version() int {
+	if versioned, ok := f.parser(diagnostic.Lenient).(Versioned); ok {
+		return versioned.Version()
+	}
+	return 0
+}
+
+func (f *format[T]) NewDocument() interface{} {
+	return new(T)
+}
+
+func (f *format[T]) Lint(documents []interface{}) diagnostic.List {
+	linter, ok := f.parser(diagnostic.Lenient).(Linter[T])
+	if !ok {
+		return nil
+	}
+	typed := make([]*T, 0, len(documents))
+	for _, document := range documents {
+		typed = append(typed, document.(*T))
+	}
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	linter.Lint(typed, report)
+	return report.Diagnostics
+}
+
+func (f *format[T]) Adapter() embedding.Adapter[interface{}] {
+	return &erasedAdapter[T]{adapter: f.adapter()}
+}
+
+func erase[T any](documents []*T) []interface{} {
+	erased := make([]interface{}, len(documents))
+	for i, document := range documents {
+		erased[i] = document
+	}
+	return erased
+}
+
+// erasedAdapter lets a DocumentAdapter[*T] embed documents held as interface{}.
+type erasedAdapter[T any] struct {
+	adapter DocumentAdapter[*T]
+}
+
+func (a *erasedAdapter[T]) GetContent(document interface{}) string {
+	return a.adapter.GetContent(document.(*T))
+}
+
+func (a *erasedAdapter[T]) StoreEmbedding(document interface{}, embedding []float32) {
+	a.adapter.StoreEmbedding(document.(*T), embedding)
+}
+
+func (a *erasedAdapter[T]) GetID(document interface{}) string {
+	return a.adapter.GetID(document.(*T))
+}
+
+func (a *erasedAdapter[T]) GetEmbedding(document interface{}) []float32 {
+	return a.adapter.GetEmbedding(document.(*T))
+}
+
+var formats = make(map[string]Format)
+
+// formatOrder keeps registration order so the first format registered for a
+// persona is its default.
+var formatOrder = make([]string, 0)
+
+// RegisterFormat makes a format available by name. It panics if the name is
+// already registered.
+func RegisterFormat(format Format) {
+	if _, ok := formats[format.Name()]; ok {
+		panic(fmt.Sprintf("format %q is already registered", format.Name()))
+	}
+	formats[format.Name()] = format
+	formatOrder = append(formatOrder, format.Name())
+}
+
+func LookupFormat(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("%q: %w", name, ErrUnknownFormat)
+	}
+	return format, nil
+}
+
+// PersonaFormat returns the first format registered for persona.
+func PersonaFormat(persona string) (Format, error) {
+	for _, name := range formatOrder {
+		if formats[name].Persona() == persona {
+			return formats[name], nil
+		}
+	}
+	return nil, fmt.Errorf("no format for persona %q: %w", persona, ErrUnknownFormat)
+}
+
+// FormatNames returns the registered format names in alphabetical order.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bibleVersifications holds the versification of each Bible translation
+// that does not follow KJV versification, by upper case abbreviation.
+var bibleVersifications = map[string]*bible.Versification{}
+
+// SetVersification checks the Bible translation abbreviated translation
+// against versification instead of KJV versification when it is parsed.
+func SetVersification(translation string, versification *bible.Versification) {
+	bibleVersifications[strings.ToUpper(translation)] = versification
+}
+
+// bibleFormat describes Bible sources written in syntax.
+func bibleFormat(syntax string) Format {
+	return NewFormat(
+		syntax, "bible", bible.DefaultLocation, "texts/bible/verses.jsonl",
+		func(mode diagnostic.Mode) Parser[bible.Verse] {
+			return &bible.Parser{Mode: mode, Syntax: syntax, Versification: bible.KJV, Versifications: bibleVersifications}
+		},
+		func() DocumentAdapter[*bible.Verse] { return &bible.EmbeddingAdapter{} },
+	)
+}
+
+func init() {
+	RegisterFormat(bibleFormat(bible.SyntaxUSFM))
+	RegisterFormat(bibleFormat(bible.SyntaxOSIS))
+	RegisterFormat(bibleFormat(bible.SyntaxUSX))
+	RegisterFormat(NewFormat(
+		"tao-plaintext", "tao", tao.DefaultLocation, "texts/tao/chapters.jsonl",
+		func(mode diagnostic.Mode) Parser[tao.Chapter] { return &tao.Parser{Mode: mode} },
+		func() DocumentAdapter[*tao.Chapter] { return &tao.EmbeddingAdapter{} },
+	))
+}
@@ -0,0 +1,123 @@
+package nvoke
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrUnstableIDs is returned when a collection holds documents stored
+// before documents had stable IDs, which cannot be matched to the corpus.
+var ErrUnstableIDs = errors.New("collection has documents without stable IDs, upload with --drop or reindex first")
+
+// ContentHashField is the field of each stored document holding its
+// ContentHash, so that a later ingest can tell which documents changed.
+const ContentHashField = "contenthash"
+
+// ContentHash identifies the content of a document apart from its
+// embedding, which is named by kb.Path. Documents hash the same whether they
+// were just parsed or read back from a generated embeddings file.
+func ContentHash(kb KnowledgeBase, document interface{}) (string, error) {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return "", err
+	}
+	delete(fields, kb.Path)
+	// Maps are encoded with sorted keys, so the hash does not depend on
+	// field order.
+	if encoded, err = json.Marshal(fields); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StoredHashes returns the content hash of every document in collection by
+// ID. Documents stored without a hash map to the empty string, so they are
+// planned as changed.
+func StoredHashes(ctx context.Context, collection *mongo.Collection) (map[string]string, error) {
+	projection := bson.D{{Key: "_id", Value: 1}, {Key: ContentHashField, Value: 1}}
+	cursor, err := collection.Find(ctx, bson.D{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	hashes := make(map[string]string)
+	for cursor.Next(ctx) {
+		var stored struct {
+			ID   bson.RawValue `bson:"_id"`
+			Hash string        `bson:"contenthash"`
+		}
+		if err := cursor.Decode(&stored); err != nil {
+			return nil, err
+		}
+		id, ok := stored.ID.StringValueOK()
+		if !ok {
+			return nil, ErrUnstableIDs
+		}
+		hashes[id] = stored.Hash
+	}
+	return hashes, cursor.Err()
+}
+
+// IngestPlan lists the document IDs an ingest adds, changes and deletes.
+type IngestPlan struct {
+	Added     []string
+	Changed   []string
+	Deleted   []string
+	Unchanged int
+	// Kept counts stored documents missing from the parsed corpus that are
+	// left in place because the plan does not prune.
+	Kept int
+}
+
+// PlanIngest compares the content hashes of a freshly parsed corpus with the
+// hashes stored for it. Stored documents that were not parsed are deleted
+// only with prune. IDs are listed in alphabetical order.
+func PlanIngest(parsed map[string]string, stored map[string]string, prune bool) IngestPlan {
+	var plan IngestPlan
+	for id, hash := range parsed {
+		switch storedHash, ok := stored[id]; {
+		case !ok:
+			plan.Added = append(plan.Added, id)
+		case storedHash != hash:
+			plan.Changed = append(plan.Changed, id)
+		default:
+			plan.Unchanged++
+		}
+	}
+	for id := range stored {
+		switch _, ok := parsed[id]; {
+		case ok:
+		case prune:
+			plan.Deleted = append(plan.Deleted, id)
+		default:
+			plan.Kept++
+		}
+	}
+	sort.Strings(plan.Added)
+	sort.Strings(plan.Changed)
+	sort.Strings(plan.Deleted)
+	return plan
+}
+
+// Empty reports whether the plan leaves the store unchanged.
+func (p IngestPlan) Empty() bool {
+	return len(p.Added) == 0 && len(p.Changed) == 0 && len(p.Deleted) == 0
+}
+
+func (p IngestPlan) String() string {
+	return fmt.Sprintf("%d added, %d changed, %d deleted, %d unchanged", len(p.Added), len(p.Changed), len(p.Deleted), p.Unchanged)
+}
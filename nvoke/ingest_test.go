@@ -0,0 +1,66 @@
+package nvoke
+
+import (
+	"nvoke/pkg/tao"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanIngest(t *testing.T) {
+	stored := map[string]string{"TAO.1": "a", "TAO.2": "b", "TAO.3": "c", "TAO.4": ""}
+	parsed := map[string]string{"TAO.1": "a", "TAO.2": "B", "TAO.4": "d", "TAO.5": "e"}
+
+	tests := []struct {
+		name     string
+		parsed   map[string]string
+		stored   map[string]string
+		prune    bool
+		expected IngestPlan
+	}{
+		{"empty store", parsed, map[string]string{}, false, IngestPlan{Added: []string{"TAO.1", "TAO.2", "TAO.4", "TAO.5"}}},
+		{"unchanged", stored, stored, true, IngestPlan{Unchanged: 4}},
+		{"kept", parsed, stored, false, IngestPlan{
+			Added:     []string{"TAO.5"},
+			Changed:   []string{"TAO.2", "TAO.4"},
+			Unchanged: 1,
+			Kept:      1,
+		}},
+		{"pruned", parsed, stored, true, IngestPlan{
+			Added:     []string{"TAO.5"},
+			Changed:   []string{"TAO.2", "TAO.4"},
+			Deleted:   []string{"TAO.3"},
+			Unchanged: 1,
+		}},
+	}
+	for _, test := range tests {
+		plan := PlanIngest(test.parsed, test.stored, test.prune)
+		assert.Equal(t, test.expected, plan, test.name)
+	}
+	assert.True(t, PlanIngest(stored, stored, true).Empty())
+	assert.False(t, PlanIngest(parsed, stored, false).Empty())
+}
+
+func TestContentHash(t *testing.T) {
+	kb := KnowledgeBase{Path: "embedding"}
+	hash := func(chapter tao.Chapter) string {
+		h, err := ContentHash(kb, chapter)
+		assert.NoError(t, err)
+		return h
+	}
+	chapter := tao.Chapter{Chapter: 1, Text: "The tao that can be told"}
+
+	tests := []struct {
+		name    string
+		changed tao.Chapter
+		same    bool
+	}{
+		{"identical", tao.Chapter{Chapter: 1, Text: "The tao that can be told"}, true},
+		{"embedded", tao.Chapter{Chapter: 1, Text: "The tao that can be told", Embedding: []float32{0.1, 0.2}}, true},
+		{"text", tao.Chapter{Chapter: 1, Text: "The tao that can be spoken"}, false},
+		{"chapter", tao.Chapter{Chapter: 2, Text: "The tao that can be told"}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.same, hash(chapter) == hash(test.changed), test.name)
+	}
+}
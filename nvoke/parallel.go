@@ -0,0 +1,60 @@
+package nvoke
+
+import (
+	"context"
+	"errors"
+	"log"
+	"nvoke/pkg/bible"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrReferenceNotFound = errors.New("reference not found")
+
+// Parallel returns the verse at book chapter:verse in every translation loaded
+// into the bible knowledge base, ordered by translation. book may be a USFM
+// book code such as GEN, a book name such as Genesis or an abbreviation.
+func (rs *RetrievalService) Parallel(ctx context.Context, book string, chapter int, verse int) ([]*bible.Verse, error) {
+	if book == "" || chapter <= 0 || verse <= 0 {
+		return nil, ErrInvalidQueryParameters
+	}
+	kb, ok := rs.KnowledgeBases["bible"]
+	if !ok {
+		return nil, ErrInvalidQueryParameters
+	}
+	name, err := rs.Catalog.ActiveCollection(ctx, kb)
+	if err != nil {
+		log.Printf("Failed to read catalog for %s.%s: %v\n", kb.Db, kb.Collection, err)
+		name = kb.Collection
+	}
+
+	books := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "bookid", Value: strings.ToUpper(book)}},
+		bson.D{{Key: "book", Value: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(book) + "$", Options: "i"}}},
+	}}}
+	if info, ok := bible.ResolveBook(book); ok {
+		books = bson.D{{Key: "bookid", Value: info.ID}}
+	}
+	filter := append(books, bson.E{Key: "chapter", Value: chapter}, bson.E{Key: "verse", Value: verse})
+	opts := options.Find().
+		SetSort(bson.D{{Key: "translation", Value: 1}}).
+		SetProjection(bson.D{{Key: kb.Path, Value: 0}})
+	cursor, err := rs.Mongodb.Database(kb.Db).Collection(name).Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Failed to find parallel verses: %v\n", err)
+		return nil, ErrSimilaritySearchFailed
+	}
+	verses := make([]*bible.Verse, 0)
+	if err := cursor.All(ctx, &verses); err != nil {
+		log.Printf("Failed to decode parallel verses: %v\n", err)
+		return nil, ErrSimilaritySearchFailed
+	}
+	if len(verses) == 0 {
+		return nil, ErrReferenceNotFound
+	}
+	return verses, nil
+}
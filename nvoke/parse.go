@@ -1,5 +1,24 @@
 package nvoke
 
+import "nvoke/pkg/diagnostic"
+
+// Parser reads the documents of a source text. Parse returns an error for
+// problems that stop the parse, such as unreadable files or, in strict mode,
+// malformed input. Diagnostics returns every problem found by the last call
+// to Parse, including those that were skipped in lenient mode.
 type Parser[T any] interface {
-	Parse(location string) []*T
+	Parse(location string) ([]*T, error)
+	Diagnostics() diagnostic.List
+}
+
+// Linter is implemented by parsers that can check the documents they produced
+// for problems that are not parse errors, such as documents without text.
+type Linter[T any] interface {
+	Lint(documents []*T, report *diagnostic.Reporter)
+}
+
+// Versioned is implemented by parsers whose output is versioned, so that
+// files of embedded documents can record which version they were parsed with.
+type Versioned interface {
+	Version() int
 }
@@ -0,0 +1,157 @@
+package nvoke
+
+import (
+	"fmt"
+	"log"
+	"nvoke/pkg/bible"
+	"nvoke/pkg/scripture"
+	"sort"
+	"strings"
+)
+
+// MaxPinnedVerses caps the verses pinned into a completion context for the
+// references in a question, so that citing a whole book cannot crowd out
+// the rest of the context.
+const MaxPinnedVerses = 50
+
+// Passages looks up verses of the parsed Bible corpus by reference and by the
+// Strong's numbers of their words.
+type Passages struct {
+	books        map[string][]*bible.Verse
+	translations []string
+	lexicon      *bible.Lexicon
+}
+
+// NewPassages indexes verses by translation and book, keeping corpus order.
+func NewPassages(verses []*bible.Verse) *Passages {
+	passages := &Passages{books: make(map[string][]*bible.Verse), lexicon: bible.NewLexicon(verses)}
+	for _, verse := range verses {
+		key := passageKey(verse.Translation, verse.BookID)
+		if !contains(passages.translations, verse.Translation) {
+			passages.translations = append(passages.translations, verse.Translation)
+		}
+		passages.books[key] = append(passages.books[key], verse)
+	}
+	sort.Strings(passages.translations)
+	return passages
+}
+
+// Translations returns the loaded translations in alphabetical order.
+func (p *Passages) Translations() []string {
+	return p.translations
+}
+
+// Lookup returns the verses of each reference in each of translations. With
+// no translations the first loaded translation is used.
+func (p *Passages) Lookup(references []scripture.Reference, translations []string) ([]*bible.Verse, error) {
+	if len(translations) == 0 && len(p.translations) > 0 {
+		translations = p.translations[:1]
+	}
+	verses := make([]*bible.Verse, 0)
+	for _, translation := range translations {
+		for _, reference := range references {
+			for _, verse := range p.books[passageKey(translation, reference.Book)] {
+				if reference.Contains(verse.Chapter, verse.Verse) {
+					verses = append(verses, verse)
+				}
+			}
+		}
+	}
+	if len(verses) == 0 {
+		return nil, ErrReferenceNotFound
+	}
+	return verses, nil
+}
+
+// Lexicon returns the Strong's number index of the corpus.
+func (p *Passages) Lexicon() *bible.Lexicon {
+	return p.lexicon
+}
+
+// Word returns the lexicon entry for a Strong's number or lemma and the
+// verses using it in each of translations. With no translations the first
+// loaded translation is used.
+func (p *Passages) Word(word string, translations []string) (*bible.LexiconEntry, []*bible.Verse, error) {
+	entry, ok := p.lexicon.Lookup(word)
+	if !ok {
+		return nil, nil, ErrReferenceNotFound
+	}
+	return entry, p.translated(entry.Verses, translations), nil
+}
+
+// translated returns the verses in any of translations, or in the first
+// loaded translation when there are none.
+func (p *Passages) translated(verses []*bible.Verse, translations []string) []*bible.Verse {
+	if len(translations) == 0 && len(p.translations) > 0 {
+		translations = p.translations[:1]
+	}
+	selected := make([]*bible.Verse, 0)
+	for _, verse := range verses {
+		for _, translation := range translations {
+			if strings.EqualFold(verse.Translation, translation) {
+				selected = append(selected, verse)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func passageKey(translation string, book string) string {
+	return fmt.Sprintf("%s.%s", strings.ToUpper(translation), book)
+}
+
+// pin prepends the verses cited by the question to the search results,
+// dropping any search result that duplicates a pinned verse. Strong's
+// numbers and lemmas in the question pin their lexicon entry followed by the
+// verses using the word.
+func (p *Passages) pin(query Query, results []interface{}) []interface{} {
+	documents := make([]interface{}, 0)
+	verses := make([]*bible.Verse, 0)
+	if references := scripture.Find(query.Query); len(references) > 0 {
+		if cited, err := p.Lookup(references, query.Translations); err == nil {
+			verses = append(verses, cited...)
+		}
+	}
+	for _, entry := range p.lexicon.Find(query.Query) {
+		documents = append(documents, entry)
+		verses = append(verses, p.translated(entry.Verses, query.Translations)...)
+	}
+	if len(documents) == 0 && len(verses) == 0 {
+		return results
+	}
+	if len(verses) > MaxPinnedVerses {
+		log.Printf("Pinning the first %d of %d cited verses\n", MaxPinnedVerses, len(verses))
+		verses = verses[:MaxPinnedVerses]
+	}
+	pinned := make(map[string]bool, len(verses))
+	combined := make([]interface{}, 0, len(documents)+len(verses)+len(results))
+	combined = append(combined, documents...)
+	for _, verse := range verses {
+		if pinned[verseKey(verse)] {
+			continue
+		}
+		pinned[verseKey(verse)] = true
+		combined = append(combined, verse)
+	}
+	for _, result := range results {
+		if verse, ok := result.(*bible.Verse); ok && pinned[verseKey(verse)] {
+			continue
+		}
+		combined = append(combined, result)
+	}
+	return combined
+}
+
+func verseKey(verse *bible.Verse) string {
+	return fmt.Sprintf("%s.%d.%d", passageKey(verse.Translation, verse.BookID), verse.Chapter, verse.Verse)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
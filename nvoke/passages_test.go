@@ -0,0 +1,84 @@
+package nvoke
+
+import (
+	"nvoke/pkg/bible"
+	"nvoke/pkg/scripture"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testVerses() []*bible.Verse {
+	return []*bible.Verse{
+		{Translation: "KJV", BookID: "GEN", Chapter: 1, Verse: 1, Text: "In the beginning God created the heaven and the earth.",
+			Words: []bible.Word{{Text: "God", Strong: []string{"H430"}, Lemma: "elohim"}}},
+		{Translation: "KJV", BookID: "JHN", Chapter: 3, Verse: 16, Text: "For God so loved the world"},
+		{Translation: "KJV", BookID: "JHN", Chapter: 3, Verse: 17, Text: "For God sent not his Son"},
+		{Translation: "WEB", BookID: "JHN", Chapter: 3, Verse: 16, Text: "For God so loved the world"},
+	}
+}
+
+func TestPassages_Lookup(t *testing.T) {
+	passages := NewPassages(testVerses())
+	assert.Equal(t, []string{"KJV", "WEB"}, passages.Translations())
+
+	tests := []struct {
+		text         string
+		translations []string
+		expected     []string
+	}{
+		{"John 3:16", nil, []string{"KJV.JHN.3.16"}},
+		{"John 3:16-17", []string{"kjv"}, []string{"KJV.JHN.3.16", "KJV.JHN.3.17"}},
+		{"John 3", []string{"WEB", "KJV"}, []string{"WEB.JHN.3.16", "KJV.JHN.3.16", "KJV.JHN.3.17"}},
+		{"Gen 1:1; John 3:17", nil, []string{"KJV.GEN.1.1", "KJV.JHN.3.17"}},
+	}
+	for _, test := range tests {
+		references, err := scripture.Parse(test.text)
+		assert.NoError(t, err, test.text)
+		verses, err := passages.Lookup(references, test.translations)
+		assert.NoError(t, err, test.text)
+		assert.Equal(t, test.expected, verseKeys(verses), test.text)
+	}
+
+	for _, text := range []string{"John 4:1", "Rev 1:1", "John 3:18"} {
+		references, err := scripture.Parse(text)
+		assert.NoError(t, err, text)
+		_, err = passages.Lookup(references, nil)
+		assert.ErrorIs(t, err, ErrReferenceNotFound, text)
+	}
+	references, _ := scripture.Parse("Gen 1:1")
+	_, err := passages.Lookup(references, []string{"WEB"})
+	assert.ErrorIs(t, err, ErrReferenceNotFound)
+}
+
+func TestPassages_Pin(t *testing.T) {
+	verses := testVerses()
+	passages := NewPassages(verses)
+	results := []interface{}{verses[2], verses[0], "other"}
+
+	// Cited verses come first in the order cited, and search results that
+	// repeat them are dropped.
+	pinned := passages.pin(Query{Query: "What does John 3:16-17 mean?"}, results)
+	assert.Equal(t, []interface{}{verses[1], verses[2], verses[0], "other"}, pinned)
+
+	// A Strong's number pins its lexicon entry followed by its verses.
+	pinned = passages.pin(Query{Query: "Where is H430 used? See John 3:16"}, results)
+	if assert.Len(t, pinned, 5) {
+		entry, ok := pinned[0].(*bible.LexiconEntry)
+		if assert.True(t, ok) {
+			assert.Equal(t, "H430", entry.Strong)
+		}
+		assert.Equal(t, []interface{}{verses[1], verses[0], verses[2], "other"}, pinned[1:])
+	}
+
+	// Questions citing nothing leave the results alone.
+	assert.Equal(t, results, passages.pin(Query{Query: "What is love?"}, results))
+}
+
+func verseKeys(verses []*bible.Verse) []string {
+	keys := make([]string, len(verses))
+	for i, verse := range verses {
+		keys[i] = verseKey(verse)
+	}
+	return keys
+}
@@ -18,13 +18,33 @@ type KnowledgeBase struct {
 	Collection string
 	Limit      int
 	Candidates int
-	persona    Persona
+	// Dimensions truncates stored and query embeddings to a shorter prefix.
+	// Zero keeps the full size produced by the generator.
+	Dimensions int
+	// Filters are document fields indexed alongside the vectors so that
+	// searches can be restricted to matching documents.
+	Filters []string
+	// Boosts multiplies the similarity score of documents in which a boolean
+	// field is true by the field's factor. More than Limit results are
+	// searched so that boosted documents can displace less similar ones.
+	Boosts   map[string]float64
+	persona  Persona
+	document func() interface{}
 }
 
 func (kb *KnowledgeBase) Persona() Persona {
 	return kb.persona
 }
 
+// NewDocument returns a pointer to decode a document of kb into.
+func (kb *KnowledgeBase) NewDocument() interface{} {
+	if kb.document == nil {
+		var document interface{}
+		return &document
+	}
+	return kb.document()
+}
+
 var KnowledgeBases = map[string]KnowledgeBase{
 	"bible": {
 		Index:      "embedding",
@@ -33,7 +53,12 @@ var KnowledgeBases = map[string]KnowledgeBase{
 		Collection: "verses",
 		Limit:      20,
 		Candidates: 200,
-		persona:    &bible.Persona{},
+		Filters:    []string{"translation", "bookid", "strongs", "wordsofjesus"},
+		// The persona speaks as Jesus, so his own words are preferred
+		// over equally similar narration.
+		Boosts:   map[string]float64{"wordsofjesus": 1.05},
+		persona:  &bible.Persona{},
+		document: func() interface{} { return &bible.Verse{} },
 	},
 	"tao": {
 		Index:      "embedding",
@@ -42,6 +67,8 @@ var KnowledgeBases = map[string]KnowledgeBase{
 		Collection: "chapters",
 		Limit:      20,
 		Candidates: 200,
+		Filters:    []string{"translation"},
 		persona:    &tao.Persona{},
+		document:   func() interface{} { return &tao.Chapter{} },
 	},
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"nvoke/pkg/embedding"
+	"sync"
 
 	"github.com/sashabaranov/go-openai"
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,6 +18,7 @@ var ErrEmbeddingGenerationFailed = errors.New("embedding generation failed")
 var ErrSimilaritySearchFailed = errors.New("similarity search failed")
 var ErrChatCompletionContextBuildFailed = errors.New("failed to build completion context")
 var ErrChatCompletionFailed = errors.New("failed to create chat completion")
+var ErrEmbeddingModelMismatch = errors.New("query embedding model does not match the knowledge base")
 
 // RetrievalService holds the parameters needed to serve completion requests.
 type RetrievalService struct {
@@ -27,6 +29,15 @@ type RetrievalService struct {
 	Limit          int
 	Candidates     int
 	KnowledgeBases map[string]KnowledgeBase
+	Catalog        *Catalog
+	// Passages, when set, pins the Bible verses a question cites into the
+	// search results regardless of their similarity.
+	Passages *Passages
+	// StrictProvenance rejects queries when the Generator does not match the
+	// model that embedded the knowledge base instead of only logging a warning.
+	StrictProvenance bool
+
+	verified sync.Map
 }
 
 func NewRetrievalService(mongodb *mongo.Client, generator embedding.Generator, openai *openai.Client) *RetrievalService {
@@ -35,6 +46,7 @@ func NewRetrievalService(mongodb *mongo.Client, generator embedding.Generator, o
 		OpenAI:         openai,
 		Generator:      generator,
 		KnowledgeBases: KnowledgeBases,
+		Catalog:        NewCatalog(mongodb),
 	}
 }
 
@@ -53,25 +65,46 @@ func (rs *RetrievalService) SemanticSearch(ctx context.Context, query Query) ([]
 		return nil, ErrInvalidQueryParameters
 	}
 
-	queryEmbedding, err := rs.Generator.GenerateEmbedding(ctx, query.Query)
+	prefilter, err := searchFilter(knowledgeBase, query)
+	if err != nil {
+		return nil, err
+	}
+
+	generator := rs.Generator
+	if knowledgeBase.Dimensions > 0 {
+		generator = embedding.NewTruncatingGenerator(generator, knowledgeBase.Dimensions)
+	}
+
+	collectionName, err := rs.resolveCollection(ctx, knowledgeBase, generator)
+	if err != nil {
+		return nil, err
+	}
+
+	queryEmbedding, err := generator.GenerateEmbedding(ctx, query.Query)
 	if err != nil {
 		log.Printf("Failed to generate embedding for the query: %v\n", err)
 		return nil, ErrEmbeddingGenerationFailed
 	}
 
-	collection := rs.Mongodb.Database(knowledgeBase.Db).Collection(knowledgeBase.Collection)
+	collection := rs.Mongodb.Database(knowledgeBase.Db).Collection(collectionName)
+	search := bson.D{
+		{Key: "index", Value: knowledgeBase.Index},
+		{Key: "path", Value: knowledgeBase.Path},
+		{Key: "queryVector", Value: queryEmbedding},
+		{Key: "numCandidates", Value: knowledgeBase.Candidates},
+		{Key: "limit", Value: searchLimit(knowledgeBase)},
+	}
+	if len(prefilter) > 0 {
+		search = append(search, bson.E{Key: "filter", Value: prefilter})
+	}
 	filter := bson.A{
-		bson.D{
-			{Key: "$vectorSearch",
-				Value: bson.D{
-					{Key: "index", Value: knowledgeBase.Index},
-					{Key: "path", Value: knowledgeBase.Path},
-					{Key: "queryVector", Value: queryEmbedding},
-					{Key: "numCandidates", Value: knowledgeBase.Candidates},
-					{Key: "limit", Value: knowledgeBase.Limit},
-				},
-			},
-		},
+		bson.D{{Key: "$vectorSearch", Value: search}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: knowledgeBase.Path, Value: 0}}}},
+	}
+	if len(knowledgeBase.Boosts) > 0 {
+		filter = append(filter, bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: scoreField, Value: bson.D{{Key: "$meta", Value: "vectorSearchScore"}}},
+		}}})
 	}
 	cursor, err := collection.Aggregate(ctx, filter)
 	if err != nil {
@@ -80,16 +113,75 @@ func (rs *RetrievalService) SemanticSearch(ctx context.Context, query Query) ([]
 	}
 	defer cursor.Close(ctx)
 	results := make([]interface{}, 0)
+	scores := make([]float64, 0)
 	for cursor.Next(ctx) {
-		var data interface{}
-		if err := cursor.Decode(&data); err != nil {
+		data := knowledgeBase.NewDocument()
+		if err := cursor.Decode(data); err != nil {
 			return nil, fmt.Errorf("failed to decode data: %v", err)
 		}
+		if generic, ok := data.(*interface{}); ok {
+			data = *generic
+		}
 		results = append(results, data)
+		scores = append(scores, boostedScore(knowledgeBase, cursor.Current))
+	}
+	if len(knowledgeBase.Boosts) > 0 {
+		results = rankBoosted(knowledgeBase, results, scores)
+	}
+	if query.Order == OrderCanonical {
+		sortCanonical(results)
+	}
+	if query.Align && query.Persona == "tao" {
+		if results, err = rs.align(ctx, query, results); err != nil {
+			return nil, err
+		}
+	}
+	if rs.Passages != nil && query.Persona == "bible" {
+		results = rs.Passages.pin(query, results)
 	}
 	return results, nil
 }
 
+// resolveCollection returns the collection currently serving kb and checks
+// that generator produces embeddings comparable with those stored in it.
+// Mismatches are only warned about once per collection and provenance, so a
+// swap or rollback is verified again. With StrictProvenance a catalog that
+// cannot be read fails the query rather than skipping the check.
+func (rs *RetrievalService) resolveCollection(ctx context.Context, kb KnowledgeBase, generator embedding.Generator) (string, error) {
+	entry, err := rs.Catalog.Get(ctx, kb)
+	if err != nil && err != ErrNotCataloged {
+		log.Printf("Failed to read catalog for %s.%s: %v\n", kb.Db, kb.Collection, err)
+		if rs.StrictProvenance {
+			return "", ErrSimilaritySearchFailed
+		}
+		return kb.Collection, nil
+	}
+	if entry == nil {
+		entry = &CatalogEntry{Collection: kb.Collection}
+	}
+	name := entry.ActiveCollection()
+	key := kb.Db + "." + name
+	verifiedKey := key + " " + entry.Provenance.String()
+	if _, ok := rs.verified.Load(verifiedKey); ok {
+		return name, nil
+	}
+
+	generated, ok := embedding.Describe(generator)
+	if !ok || generated.Model == "" {
+		return name, nil
+	}
+	if entry.Provenance.Model == "" {
+		log.Printf("No embedding provenance recorded for %s, unable to verify model %s\n", key, generated)
+	} else if !generated.Matches(entry.Provenance) {
+		log.Printf("Query model %s does not match %s embedded with %s\n", generated, key, entry.Provenance)
+		if rs.StrictProvenance {
+			return "", ErrEmbeddingModelMismatch
+		}
+	}
+	rs.verified.Store(verifiedKey, true)
+	return name, nil
+}
+
 func (rs *RetrievalService) CreateChatCompletion(ctx context.Context, query Query) (string, error) {
 	knowledgeBase, ok := rs.KnowledgeBases[query.Persona]
 	if !ok {
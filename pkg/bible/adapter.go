@@ -1,6 +1,9 @@
 package bible
 
-import "nvoke/pkg/embedding"
+import (
+	"fmt"
+	"nvoke/pkg/embedding"
+)
 
 type EmbeddingAdapter struct{}
 
@@ -15,3 +18,11 @@ func (vh *EmbeddingAdapter) GetContent(verse *Verse) string {
 func (vh *EmbeddingAdapter) StoreEmbedding(verse *Verse, embedding []float32) {
 	verse.Embedding = embedding
 }
+
+func (vh *EmbeddingAdapter) GetID(verse *Verse) string {
+	return fmt.Sprintf("%s.%s.%d.%d", verse.Translation, verse.BookID, verse.Chapter, verse.Verse)
+}
+
+func (vh *EmbeddingAdapter) GetEmbedding(verse *Verse) []float32 {
+	return verse.Embedding
+}
@@ -1,6 +1,7 @@
 package bible
 
 type Book struct {
+	ID       string     `json:"book_id"`
 	Name     string     `json:"book_name"`
 	Chapters []*Chapter `json:"chapters"`
 }
@@ -11,13 +12,55 @@ type Chapter struct {
 }
 
 type Verse struct {
-	Book      string    `json:"book"`
-	Chapter   int       `json:"chapter"`
-	Verse     int       `json:"verse"`
-	Text      string    `json:"text"`
+	// Translation is the abbreviation of the translation the verse was read
+	// from, such as KJV.
+	Translation     string   `json:"translation"`
+	BookID          string   `json:"book_id"`
+	Book            string   `json:"book"`
+	Chapter         int      `json:"chapter"`
+	Verse           int      `json:"verse"`
+	Text            string   `json:"text"`
+	Paragraph       bool     `json:"paragraph,omitempty"`
+	Headings        []string `json:"headings,omitempty"`
+	Lines           []Line   `json:"lines,omitempty"`
+	Footnotes       []Note   `json:"footnotes,omitempty"`
+	CrossReferences []Note   `json:"cross_references,omitempty"`
+	// WordsOfJesus reports whether any of Text is marked with \wj as spoken
+	// by Jesus, and JesusSpans holds those parts of Text.
+	WordsOfJesus bool     `json:"words_of_jesus,omitempty"`
+	JesusSpans   []string `json:"jesus_spans,omitempty"`
+	// Words are the words of Text marked with original-language attributes
+	// and Strongs lists their distinct Strong's numbers, so that verses can
+	// be filtered by the words they translate.
+	Words     []Word    `json:"words,omitempty"`
+	Strongs   []string  `json:"strongs,omitempty"`
 	Embedding []float32 `json:"embedding,omitempty"`
 }
 
+// Line is a line of poetry within a verse. Level is the indentation level
+// given by the \q marker.
+type Line struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// Word is a word of verse text marked with \w and the attributes that tie it
+// to the original language. Strong holds Strong's numbers such as H7225.
+type Word struct {
+	Text   string   `json:"text"`
+	Strong []string `json:"strong,omitempty"`
+	Lemma  string   `json:"lemma,omitempty"`
+}
+
+// Note is a footnote or cross reference. Reference is the verse the note
+// refers to and Text is the note body, or the target references for a cross
+// reference.
+type Note struct {
+	Caller    string `json:"caller,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	Text      string `json:"text"`
+}
+
 func NewBook(name string) *Book {
 	return &Book{
 		Name: name,
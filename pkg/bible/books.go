@@ -0,0 +1,222 @@
+package bible
+
+import (
+	"sort"
+	"strings"
+)
+
+// Testaments group the canonical books.
+const (
+	OldTestament = "OT"
+	Deuterocanon = "DC"
+	NewTestament = "NT"
+)
+
+// BookInfo identifies a canonical book. ID is the USFM book code used on \id
+// lines and OSIS is the book's osisID abbreviation. Aliases are additional
+// abbreviations accepted by ResolveBook besides the ID, OSIS code and name.
+// Order is the book's 1-based position in Books.
+type BookInfo struct {
+	ID        string
+	OSIS      string
+	Name      string
+	Aliases   []string
+	Testament string
+	Order     int
+}
+
+// Books lists the books of the Protestant canon and the deuterocanonical books
+// of the KJV Apocrypha in canonical order, with the Apocrypha between the
+// testaments as in the 1611 KJV.
+var Books = []BookInfo{
+	{ID: "GEN", OSIS: "Gen", Name: "Genesis", Aliases: []string{"ge", "gn"}},
+	{ID: "EXO", OSIS: "Exod", Name: "Exodus", Aliases: []string{"ex", "exo"}},
+	{ID: "LEV", OSIS: "Lev", Name: "Leviticus", Aliases: []string{"le", "lv"}},
+	{ID: "NUM", OSIS: "Num", Name: "Numbers", Aliases: []string{"nu", "nm", "nb"}},
+	{ID: "DEU", OSIS: "Deut", Name: "Deuteronomy", Aliases: []string{"de", "dt"}},
+	{ID: "JOS", OSIS: "Josh", Name: "Joshua", Aliases: []string{"jsh"}},
+	{ID: "JDG", OSIS: "Judg", Name: "Judges", Aliases: []string{"jg", "jdgs"}},
+	{ID: "RUT", OSIS: "Ruth", Name: "Ruth", Aliases: []string{"rth", "ru"}},
+	{ID: "1SA", OSIS: "1Sam", Name: "1 Samuel", Aliases: []string{"1s", "1sm"}},
+	{ID: "2SA", OSIS: "2Sam", Name: "2 Samuel", Aliases: []string{"2s", "2sm"}},
+	{ID: "1KI", OSIS: "1Kgs", Name: "1 Kings", Aliases: []string{"1k", "1kin"}},
+	{ID: "2KI", OSIS: "2Kgs", Name: "2 Kings", Aliases: []string{"2k", "2kin"}},
+	{ID: "1CH", OSIS: "1Chr", Name: "1 Chronicles", Aliases: []string{"1chron"}},
+	{ID: "2CH", OSIS: "2Chr", Name: "2 Chronicles", Aliases: []string{"2chron"}},
+	{ID: "EZR", OSIS: "Ezra", Name: "Ezra"},
+	{ID: "NEH", OSIS: "Neh", Name: "Nehemiah", Aliases: []string{"ne"}},
+	{ID: "EST", OSIS: "Esth", Name: "Esther", Aliases: []string{"es"}},
+	{ID: "JOB", OSIS: "Job", Name: "Job", Aliases: []string{"jb"}},
+	{ID: "PSA", OSIS: "Ps", Name: "Psalms", Aliases: []string{"psalm", "pss", "psm", "pslm"}},
+	{ID: "PRO", OSIS: "Prov", Name: "Proverbs", Aliases: []string{"prv", "pr"}},
+	{ID: "ECC", OSIS: "Eccl", Name: "Ecclesiastes", Aliases: []string{"eccles", "qoh"}},
+	{ID: "SNG", OSIS: "Song", Name: "Song of Solomon", Aliases: []string{"sos", "songofsongs", "canticles"}},
+	{ID: "ISA", OSIS: "Isa", Name: "Isaiah"},
+	{ID: "JER", OSIS: "Jer", Name: "Jeremiah", Aliases: []string{"je", "jr"}},
+	{ID: "LAM", OSIS: "Lam", Name: "Lamentations", Aliases: []string{"la"}},
+	{ID: "EZK", OSIS: "Ezek", Name: "Ezekiel", Aliases: []string{"eze"}},
+	{ID: "DAN", OSIS: "Dan", Name: "Daniel", Aliases: []string{"da", "dn"}},
+	{ID: "HOS", OSIS: "Hos", Name: "Hosea", Aliases: []string{"ho"}},
+	{ID: "JOL", OSIS: "Joel", Name: "Joel", Aliases: []string{"joe", "jl"}},
+	{ID: "AMO", OSIS: "Amos", Name: "Amos", Aliases: []string{"am"}},
+	{ID: "OBA", OSIS: "Obad", Name: "Obadiah", Aliases: []string{"ob"}},
+	{ID: "JON", OSIS: "Jonah", Name: "Jonah", Aliases: []string{"jnh"}},
+	{ID: "MIC", OSIS: "Mic", Name: "Micah", Aliases: []string{"mc"}},
+	{ID: "NAM", OSIS: "Nah", Name: "Nahum", Aliases: []string{"na"}},
+	{ID: "HAB", OSIS: "Hab", Name: "Habakkuk", Aliases: []string{"hb"}},
+	{ID: "ZEP", OSIS: "Zeph", Name: "Zephaniah", Aliases: []string{"zp"}},
+	{ID: "HAG", OSIS: "Hag", Name: "Haggai", Aliases: []string{"hg"}},
+	{ID: "ZEC", OSIS: "Zech", Name: "Zechariah", Aliases: []string{"zc"}},
+	{ID: "MAL", OSIS: "Mal", Name: "Malachi", Aliases: []string{"ml"}},
+	{ID: "TOB", OSIS: "Tob", Name: "Tobit", Aliases: []string{"tb"}},
+	{ID: "JDT", OSIS: "Jdt", Name: "Judith", Aliases: []string{"jth"}},
+	{ID: "ESG", OSIS: "EsthGr", Name: "Esther (Greek)", Aliases: []string{"esthergreek", "addesth"}},
+	{ID: "WIS", OSIS: "Wis", Name: "Wisdom of Solomon", Aliases: []string{"wisdom"}},
+	{ID: "SIR", OSIS: "Sir", Name: "Sirach", Aliases: []string{"ecclus", "ecclesiasticus"}},
+	{ID: "BAR", OSIS: "Bar", Name: "Baruch"},
+	{ID: "S3Y", OSIS: "PrAzar", Name: "Song of the Three Young Men", Aliases: []string{"songofthree", "azariah"}},
+	{ID: "SUS", OSIS: "Sus", Name: "Susanna"},
+	{ID: "BEL", OSIS: "Bel", Name: "Bel and the Dragon"},
+	{ID: "1MA", OSIS: "1Macc", Name: "1 Maccabees", Aliases: []string{"1mac"}},
+	{ID: "2MA", OSIS: "2Macc", Name: "2 Maccabees", Aliases: []string{"2mac"}},
+	{ID: "1ES", OSIS: "1Esd", Name: "1 Esdras"},
+	{ID: "MAN", OSIS: "PrMan", Name: "Prayer of Manasseh", Aliases: []string{"manasseh"}},
+	{ID: "2ES", OSIS: "2Esd", Name: "2 Esdras"},
+	{ID: "MAT", OSIS: "Matt", Name: "Matthew", Aliases: []string{"mt"}},
+	{ID: "MRK", OSIS: "Mark", Name: "Mark", Aliases: []string{"mk", "mr"}},
+	{ID: "LUK", OSIS: "Luke", Name: "Luke", Aliases: []string{"lk"}},
+	{ID: "JHN", OSIS: "John", Name: "John", Aliases: []string{"jn", "joh"}},
+	{ID: "ACT", OSIS: "Acts", Name: "Acts", Aliases: []string{"ac"}},
+	{ID: "ROM", OSIS: "Rom", Name: "Romans", Aliases: []string{"ro", "rm"}},
+	{ID: "1CO", OSIS: "1Cor", Name: "1 Corinthians"},
+	{ID: "2CO", OSIS: "2Cor", Name: "2 Corinthians"},
+	{ID: "GAL", OSIS: "Gal", Name: "Galatians", Aliases: []string{"ga"}},
+	{ID: "EPH", OSIS: "Eph", Name: "Ephesians", Aliases: []string{"ephes"}},
+	{ID: "PHP", OSIS: "Phil", Name: "Philippians", Aliases: []string{"pp"}},
+	{ID: "COL", OSIS: "Col", Name: "Colossians"},
+	{ID: "1TH", OSIS: "1Thess", Name: "1 Thessalonians", Aliases: []string{"1thes"}},
+	{ID: "2TH", OSIS: "2Thess", Name: "2 Thessalonians", Aliases: []string{"2thes"}},
+	{ID: "1TI", OSIS: "1Tim", Name: "1 Timothy"},
+	{ID: "2TI", OSIS: "2Tim", Name: "2 Timothy"},
+	{ID: "TIT", OSIS: "Titus", Name: "Titus", Aliases: []string{"ti"}},
+	{ID: "PHM", OSIS: "Phlm", Name: "Philemon", Aliases: []string{"philem", "pm"}},
+	{ID: "HEB", OSIS: "Heb", Name: "Hebrews"},
+	{ID: "JAS", OSIS: "Jas", Name: "James", Aliases: []string{"jm"}},
+	{ID: "1PE", OSIS: "1Pet", Name: "1 Peter", Aliases: []string{"1pt", "1p"}},
+	{ID: "2PE", OSIS: "2Pet", Name: "2 Peter", Aliases: []string{"2pt", "2p"}},
+	{ID: "1JN", OSIS: "1John", Name: "1 John", Aliases: []string{"1jo", "1jhn"}},
+	{ID: "2JN", OSIS: "2John", Name: "2 John", Aliases: []string{"2jo", "2jhn"}},
+	{ID: "3JN", OSIS: "3John", Name: "3 John", Aliases: []string{"3jo", "3jhn"}},
+	{ID: "JUD", OSIS: "Jude", Name: "Jude", Aliases: []string{"jd"}},
+	{ID: "REV", OSIS: "Rev", Name: "Revelation", Aliases: []string{"re", "revelations", "apocalypse"}},
+}
+
+var booksByID = make(map[string]BookInfo)
+var booksByOSIS = make(map[string]BookInfo)
+var booksByAlias = make(map[string]BookInfo)
+
+func init() {
+	testament := OldTestament
+	for i := range Books {
+		switch Books[i].ID {
+		case "TOB":
+			testament = Deuterocanon
+		case "MAT":
+			testament = NewTestament
+		}
+		Books[i].Testament = testament
+		Books[i].Order = i + 1
+	}
+	for _, book := range Books {
+		booksByID[book.ID] = book
+		booksByOSIS[book.OSIS] = book
+		for _, alias := range append([]string{book.ID, book.OSIS, book.Name}, book.Aliases...) {
+			booksByAlias[normalizeBook(alias)] = book
+		}
+	}
+}
+
+// ResolveBook returns the book named by a name, abbreviation or code, such as
+// "John", "Jn", "JHN", "1 Cor" or "I Corinthians". Case, spaces and periods
+// are ignored.
+func ResolveBook(name string) (BookInfo, bool) {
+	book, ok := booksByAlias[normalizeBook(name)]
+	return book, ok
+}
+
+var ordinals = map[string]string{
+	"i": "1", "ii": "2", "iii": "3",
+	"first": "1", "second": "2", "third": "3",
+	"1st": "1", "2nd": "2", "3rd": "3",
+}
+
+func normalizeBook(name string) string {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(name, ".", " ")))
+	if len(fields) > 1 {
+		if number, ok := ordinals[fields[0]]; ok {
+			fields[0] = number
+		}
+	}
+	return strings.Join(fields, "")
+}
+
+// LookupBook returns the canonical book with the USFM code id.
+func LookupBook(id string) (BookInfo, bool) {
+	book, ok := booksByID[id]
+	return book, ok
+}
+
+// LookupOSISBook returns the canonical book with the OSIS abbreviation osis.
+func LookupOSISBook(osis string) (BookInfo, bool) {
+	book, ok := booksByOSIS[osis]
+	return book, ok
+}
+
+// TestamentBooks returns the books of testament in canonical order.
+func TestamentBooks(testament string) []BookInfo {
+	books := make([]BookInfo, 0)
+	for _, book := range Books {
+		if book.Testament == testament {
+			books = append(books, book)
+		}
+	}
+	return books
+}
+
+// BookRange returns the books from first to last inclusive in canonical
+// order, such as Matthew through John for "Matthew" and "John".
+func BookRange(first string, last string) ([]BookInfo, bool) {
+	from, ok := ResolveBook(first)
+	if !ok {
+		return nil, false
+	}
+	to, ok := ResolveBook(last)
+	if !ok || to.Order < from.Order {
+		return nil, false
+	}
+	return Books[from.Order-1 : to.Order], true
+}
+
+// CanonicalOrder returns the position of the book with USFM code id in Books,
+// or a position after every canonical book for unknown codes.
+func CanonicalOrder(id string) int {
+	if book, ok := booksByID[id]; ok {
+		return book.Order
+	}
+	return len(Books) + 1
+}
+
+// SortVerses sorts verses into canonical order by book, chapter and verse,
+// keeping the relative order of verses at the same reference.
+func SortVerses(verses []*Verse) {
+	sort.SliceStable(verses, func(i, j int) bool {
+		a, b := verses[i], verses[j]
+		if a.BookID != b.BookID {
+			return CanonicalOrder(a.BookID) < CanonicalOrder(b.BookID)
+		}
+		if a.Chapter != b.Chapter {
+			return a.Chapter < b.Chapter
+		}
+		return a.Verse < b.Verse
+	})
+}
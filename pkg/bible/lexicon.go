@@ -0,0 +1,159 @@
+package bible
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var strongPattern = regexp.MustCompile(`(?i)^([hg])0*(\d+)([a-z]?)$`)
+
+// NormalizeStrong returns a Strong's number in the form H7225 or G26,
+// accepting leading zeros, lower case and an OSIS "strong:" prefix.
+func NormalizeStrong(number string) (string, bool) {
+	number = strings.TrimPrefix(strings.TrimSpace(number), "strong:")
+	match := strongPattern.FindStringSubmatch(number)
+	if match == nil {
+		return "", false
+	}
+	return strings.ToUpper(match[1]) + match[2] + strings.ToLower(match[3]), true
+}
+
+// strongNumbers parses an attribute holding one or more Strong's numbers
+// separated by spaces or commas, dropping values that are not numbers.
+func strongNumbers(value string) []string {
+	numbers := make([]string, 0)
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if number, ok := NormalizeStrong(field); ok {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// verseStrongs returns the distinct Strong's numbers of words in the order
+// they first appear.
+func verseStrongs(words []Word) []string {
+	seen := make(map[string]bool)
+	numbers := make([]string, 0)
+	for _, word := range words {
+		for _, number := range word.Strong {
+			if !seen[number] {
+				seen[number] = true
+				numbers = append(numbers, number)
+			}
+		}
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+	return numbers
+}
+
+// LexiconEntry summarizes how the verses of a corpus translate the
+// original-language word with a Strong's number.
+type LexiconEntry struct {
+	Strong string `json:"strong"`
+	Lemma  string `json:"lemma,omitempty"`
+	// Renderings are the words used to translate it, most frequent first.
+	Renderings  []Rendering `json:"renderings"`
+	Occurrences int         `json:"occurrences"`
+	// Verses are the verses using the word, in corpus order.
+	Verses []*Verse `json:"-"`
+}
+
+// Rendering is one way a word is translated and how often.
+type Rendering struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// Lexicon indexes verses by the Strong's numbers and lemmas of their words.
+type Lexicon struct {
+	entries map[string]*LexiconEntry
+	lemmas  map[string][]string
+}
+
+// NewLexicon indexes the words of verses.
+func NewLexicon(verses []*Verse) *Lexicon {
+	lexicon := &Lexicon{entries: make(map[string]*LexiconEntry), lemmas: make(map[string][]string)}
+	counts := make(map[string]map[string]int)
+	for _, verse := range verses {
+		for _, word := range verse.Words {
+			for _, number := range word.Strong {
+				entry, ok := lexicon.entries[number]
+				if !ok {
+					entry = &LexiconEntry{Strong: number}
+					lexicon.entries[number] = entry
+					counts[number] = make(map[string]int)
+				}
+				if entry.Lemma == "" && word.Lemma != "" {
+					entry.Lemma = word.Lemma
+					lemma := strings.ToLower(word.Lemma)
+					lexicon.lemmas[lemma] = append(lexicon.lemmas[lemma], number)
+				}
+				if n := len(entry.Verses); n == 0 || entry.Verses[n-1] != verse {
+					entry.Verses = append(entry.Verses, verse)
+				}
+				entry.Occurrences++
+				if text := strings.ToLower(strings.Trim(word.Text, ",.;:?!()'\"")); text != "" {
+					counts[number][text]++
+				}
+			}
+		}
+	}
+	for number, entry := range lexicon.entries {
+		for text, count := range counts[number] {
+			entry.Renderings = append(entry.Renderings, Rendering{Text: text, Count: count})
+		}
+		sort.Slice(entry.Renderings, func(i, j int) bool {
+			a, b := entry.Renderings[i], entry.Renderings[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Text < b.Text
+		})
+	}
+	return lexicon
+}
+
+// Len returns the number of Strong's numbers in the lexicon.
+func (l *Lexicon) Len() int {
+	return len(l.entries)
+}
+
+// Lookup returns the entry for a Strong's number or a lemma.
+func (l *Lexicon) Lookup(word string) (*LexiconEntry, bool) {
+	if number, ok := NormalizeStrong(word); ok {
+		entry, ok := l.entries[number]
+		return entry, ok
+	}
+	if numbers := l.lemmas[strings.ToLower(strings.TrimSpace(word))]; len(numbers) > 0 {
+		return l.entries[numbers[0]], true
+	}
+	return nil, false
+}
+
+// Find returns the entries for the Strong's numbers and lemmas mentioned in
+// text, in the order they are first mentioned.
+func (l *Lexicon) Find(text string) []*LexiconEntry {
+	found := make([]*LexiconEntry, 0)
+	seen := make(map[string]bool)
+	add := func(number string) {
+		if entry, ok := l.entries[number]; ok && !seen[number] {
+			seen[number] = true
+			found = append(found, entry)
+		}
+	}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if number, ok := NormalizeStrong(word); ok {
+			add(number)
+			continue
+		}
+		for _, number := range l.lemmas[strings.ToLower(word)] {
+			add(number)
+		}
+	}
+	return found
+}
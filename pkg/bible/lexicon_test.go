@@ -0,0 +1,47 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const strongs = `\id 1CO
+\c 13
+\v 4 \w Charity|strong="G0026" lemma="agape"\w* \w suffereth long|strong="G3114"\w*, \w and|strong="G2532"\w* \w is kind|strong="G5541"\w*;
+\v 13 \w And|strong="G1161"\w* now abideth \w faith|strong="G4102"\w*, \w hope|strong="G1680"\w*, \w charity|strong="G26"\w*, these three; \w but|strong="G1161"\w* the \+w greatest|strong="G3187"\+w* of these \w is|strong="G2076"\w* \w charity|strong="G26"\w*.
+`
+
+func TestParseUSFM_Words(t *testing.T) {
+	books, err := parseUSFM(strongs, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	verses := books[0].Chapters[0].Verses
+	assert.Equal(t, "Charity suffereth long, and is kind;", verses[0].Text)
+	assert.Equal(t, Word{Text: "Charity", Strong: []string{"G26"}, Lemma: "agape"}, verses[0].Words[0])
+	assert.Equal(t, []string{"G26", "G3114", "G2532", "G5541"}, verses[0].Strongs)
+	assert.Equal(t, "And now abideth faith, hope, charity, these three; but the greatest of these is charity.", verses[1].Text)
+	assert.Contains(t, verses[1].Strongs, "G3187")
+}
+
+func TestLexicon(t *testing.T) {
+	books, err := parseUSFM(strongs, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	lexicon := NewLexicon(generateVerseDocuments(books))
+
+	entry, ok := lexicon.Lookup("g026")
+	assert.True(t, ok)
+	assert.Equal(t, "G26", entry.Strong)
+	assert.Equal(t, "agape", entry.Lemma)
+	assert.Equal(t, 3, entry.Occurrences)
+	assert.Equal(t, []Rendering{{Text: "charity", Count: 3}}, entry.Renderings)
+	assert.Len(t, entry.Verses, 2)
+
+	found := lexicon.Find("What does Agape mean, and how is G1680 used?")
+	assert.Len(t, found, 2)
+	assert.Equal(t, "G26", found[0].Strong)
+	assert.Equal(t, "G1680", found[1].Strong)
+
+	_, ok = lexicon.Lookup("G9999")
+	assert.False(t, ok)
+}
@@ -0,0 +1,27 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"regexp"
+	"strings"
+)
+
+// leftoverMarker matches USFM markup that survived parsing, such as an
+// unknown \marker or a stray word attribute list.
+var leftoverMarker = regexp.MustCompile(`\\\+?[a-z][a-z0-9-]*\*?|\|\s*[a-z-]+="`)
+
+// Lint reports verses without text and verses whose text still holds USFM
+// markup. Missing and duplicate verses are reported by Parse when the parser
+// has a Versification.
+func (p *Parser) Lint(verses []*Verse, report *diagnostic.Reporter) {
+	for _, verse := range verses {
+		label := bookLabel(verse.Translation, verse.BookID)
+		if strings.TrimSpace(verse.Text) == "" {
+			report.Errorf(0, 0, "%s %d:%d: empty text", label, verse.Chapter, verse.Verse)
+			continue
+		}
+		if marker := leftoverMarker.FindString(verse.Text); marker != "" {
+			report.Errorf(0, 0, "%s %d:%d: leftover USFM markup %q", label, verse.Chapter, verse.Verse, marker)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Lint(t *testing.T) {
+	verses := []*Verse{
+		{Translation: "KJV", BookID: "JHN", Chapter: 11, Verse: 35, Text: "Jesus wept."},
+		{Translation: "KJV", BookID: "JHN", Chapter: 11, Verse: 36, Text: " "},
+		{Translation: "KJV", BookID: "JHN", Chapter: 11, Verse: 37, Text: "And some of them said, \\add Could not\\add* this man"},
+		{Translation: "KJV", BookID: "JHN", Chapter: 11, Verse: 38, Text: "Jesus|strong=\"G2424\" therefore"},
+	}
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	(&Parser{}).Lint(verses, report)
+	messages := make([]string, 0)
+	for _, d := range report.Diagnostics {
+		messages = append(messages, d.Message)
+	}
+	assert.Equal(t, []string{
+		"KJV JHN 11:36: empty text",
+		"KJV JHN 11:37: leftover USFM markup \"\\\\add\"",
+		"KJV JHN 11:38: leftover USFM markup \"|strong=\\\"\"",
+	}, messages)
+}
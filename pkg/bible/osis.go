@@ -0,0 +1,169 @@
+package bible
+
+import (
+	"encoding/xml"
+	"fmt"
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/usfm"
+	"strings"
+)
+
+// parseOSIS builds the books in an OSIS document. Verses and chapters may be
+// containers or sID/eID milestones that span other elements.
+func parseOSIS(source string, report *diagnostic.Reporter) ([]*Book, error) {
+	reader := newXMLReader(source, report)
+	reader.element = func(start xml.StartElement) (func() error, error) {
+		return osisElement(reader, start)
+	}
+	return reader.read()
+}
+
+func osisElement(r *xmlReader, start xml.StartElement) (func() error, error) {
+	switch start.Name.Local {
+	case "header":
+		return nil, r.decoder.Skip()
+	case "div":
+		if attribute(start, "type") != "book" {
+			return nil, nil
+		}
+		osisID := attribute(start, "osisID")
+		book, ok := LookupOSISBook(osisID)
+		if !ok {
+			line, column := r.decoder.InputPos()
+			r.builder.report.Warnf(line, column, "unknown OSIS book %q", osisID)
+			book = BookInfo{ID: strings.ToUpper(osisID), OSIS: osisID, Name: osisID}
+		}
+		if err := r.marker("id", book.ID); err != nil {
+			return nil, err
+		}
+		return nil, r.marker("h", book.Name)
+	case "chapter":
+		if attribute(start, "eID") != "" {
+			return nil, nil
+		}
+		return nil, r.marker("c", osisNumber(attribute(start, "osisID")))
+	case "verse":
+		if attribute(start, "eID") != "" {
+			return nil, r.builder.endVerse()
+		}
+		if err := r.marker("v", osisNumber(attribute(start, "osisID"))+" "); err != nil {
+			return nil, err
+		}
+		if attribute(start, "sID") != "" {
+			return nil, nil
+		}
+		return r.builder.endVerse, nil
+	case "p":
+		return nil, r.marker("p", "")
+	case "milestone":
+		if attribute(start, "type") == "x-p" {
+			return nil, r.marker("p", "")
+		}
+	case "w":
+		return r.word(osisLemma(attribute(start, "lemma")))
+	case "q":
+		// Quotes spoken by Jesus are the OSIS form of \wj.
+		if eID := attribute(start, "eID"); eID != "" {
+			if !r.quotes[eID] {
+				return nil, nil
+			}
+			delete(r.quotes, eID)
+			return nil, r.emit(usfm.EndMarker, "wj", "")
+		}
+		if attribute(start, "who") != "Jesus" {
+			return nil, nil
+		}
+		if err := r.emit(usfm.Marker, "wj", ""); err != nil {
+			return nil, err
+		}
+		if sID := attribute(start, "sID"); sID != "" {
+			r.quotes[sID] = true
+			return nil, nil
+		}
+		return r.closer("wj"), nil
+	case "l":
+		level := attribute(start, "level")
+		if level == "" {
+			level = "1"
+		}
+		return nil, r.marker("q"+level, "")
+	case "title":
+		marker := "s"
+		switch {
+		case attribute(start, "type") == "main":
+			marker = "mt"
+		case attribute(start, "type") == "chapter":
+			marker = "cl"
+		case attribute(start, "type") == "psalm" || attribute(start, "canonical") == "true":
+			marker = "d"
+		}
+		if err := r.marker(marker, ""); err != nil {
+			return nil, err
+		}
+		return r.builder.endTitle, nil
+	case "note":
+		kind, reference, body := "f", "fr", "ft"
+		if attribute(start, "type") == "crossReference" {
+			kind, reference, body = "x", "xo", "xt"
+		}
+		caller := attribute(start, "n")
+		if caller == "" {
+			caller = "+"
+		}
+		if err := r.marker(kind, caller); err != nil {
+			return nil, err
+		}
+		if ref := osisReference(attribute(start, "osisRef")); ref != "" {
+			if err := r.marker(reference, ref); err != nil {
+				return nil, err
+			}
+		}
+		if err := r.emit(usfm.Marker, body, ""); err != nil {
+			return nil, err
+		}
+		return r.closer(kind), nil
+	}
+	return nil, nil
+}
+
+// osisNumber returns the verse or chapter number at the end of an osisID such
+// as Gen.1.2. A space separated list of verses becomes a range such as 2-3.
+func osisNumber(osisID string) string {
+	ids := strings.Fields(osisID)
+	if len(ids) == 0 {
+		return ""
+	}
+	first := ids[0][strings.LastIndex(ids[0], ".")+1:]
+	if len(ids) == 1 {
+		return first
+	}
+	last := ids[len(ids)-1]
+	return fmt.Sprintf("%s-%s", first, last[strings.LastIndex(last, ".")+1:])
+}
+
+// osisReference converts an osisRef such as Gen.1.1 to the chapter:verse form
+// USFM uses for note references.
+func osisReference(osisRef string) string {
+	parts := strings.Split(osisRef, ".")
+	if len(parts) != 3 {
+		return osisRef
+	}
+	return parts[1] + ":" + parts[2]
+}
+
+// osisLemma splits an OSIS lemma attribute such as "strong:G26 lemma.TR:ἀγάπη"
+// into its Strong's numbers and its lemma.
+func osisLemma(value string) (string, string) {
+	strongs := make([]string, 0)
+	lemma := ""
+	for _, field := range strings.Fields(value) {
+		prefix, text, ok := strings.Cut(field, ":")
+		switch {
+		case ok && prefix == "strong":
+			strongs = append(strongs, text)
+		case ok && strings.HasPrefix(prefix, "lemma") && lemma == "":
+			lemma = text
+		}
+	}
+	return strings.Join(strongs, " "), lemma
+}
@@ -1,99 +1,157 @@
 package bible
 
 import (
-	"bufio"
 	"fmt"
+	"nvoke/pkg/diagnostic"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
-type Parser struct{}
+// DefaultLocation holds one directory of source files per translation, named
+// after the translation's abbreviation.
+const DefaultLocation = "texts/bible/"
 
-func (p *Parser) Parse(location string) []*Verse {
-	books := loadBooksFromFiles()
-	return generateVerseDocuments(books)
+// Source file syntaxes understood by Parser.
+const (
+	SyntaxUSFM = "usfm"
+	SyntaxOSIS = "osis"
+	SyntaxUSX  = "usx"
+)
+
+var syntaxes = map[string]func(source string, report *diagnostic.Reporter) ([]*Book, error){
+	SyntaxUSFM: parseUSFM,
+	SyntaxOSIS: parseOSIS,
+	SyntaxUSX:  parseUSX,
 }
 
-func ParseBook(line string) *Book {
-	parts := strings.SplitN(line, " ", 2)
-	return &Book{
-		Name: strings.TrimSpace(parts[1]),
-	}
+type Parser struct {
+	Mode diagnostic.Mode
+	// Syntax is the format of every source file. When empty it is detected
+	// separately for each file.
+	Syntax string
+	// Translation names the translation when location is a single directory
+	// of source files. It defaults to the directory name in upper case.
+	Translation string
+	// Versification, when set, checks each translation for verses that are
+	// missing, duplicated or outside the scheme and reports them as warnings.
+	Versification *Versification
+	// Versifications overrides Versification for the translations it names,
+	// keyed by upper case abbreviation, such as a Hebrew Bible checked
+	// against the Hebrew scheme in a corpus of English translations.
+	Versifications map[string]*Versification
+	diagnostics    diagnostic.List
 }
 
-func ParseVerse(line string) *Verse {
-	parts := strings.SplitN(line, " ", 2)
-	n, err := strconv.Atoi(parts[0])
+// Parse reads the USFM, OSIS or USX files in the location directory. When location
+// contains subdirectories, each one is parsed as a separate translation.
+func (p *Parser) Parse(location string) ([]*Verse, error) {
+	if location == "" {
+		location = DefaultLocation
+	}
+	report := diagnostic.NewReporter(p.Mode)
+	defer func() { p.diagnostics = report.Diagnostics }()
+
+	translations, err := translationDirectories(location)
 	if err != nil {
-		fmt.Println(err)
-		return nil
+		return nil, err
 	}
-	return &Verse{
-		Verse: n,
-		Text:  strings.TrimSpace(parts[1]),
+	verses := make([]*Verse, 0)
+	for _, directory := range translations {
+		translation := p.Translation
+		if translation == "" || len(translations) > 1 {
+			translation = strings.ToUpper(filepath.Base(filepath.Clean(directory)))
+		}
+		books, err := loadBooksFromFiles(directory, p.Syntax, report)
+		if err != nil {
+			return nil, err
+		}
+		parsed := generateVerseDocuments(books)
+		for _, verse := range parsed {
+			verse.Translation = translation
+		}
+		if versification := p.versification(translation); versification != nil {
+			report.File = directory
+			versification.Check(parsed, report)
+		}
+		verses = append(verses, parsed...)
 	}
+	return verses, nil
 }
 
-func parseBooksFile(f *os.File) []*Book {
-	scanner := bufio.NewScanner(f)
+// versification returns the scheme that translation is checked against.
+func (p *Parser) versification(translation string) *Versification {
+	if versification, ok := p.Versifications[strings.ToUpper(translation)]; ok {
+		return versification
+	}
+	return p.Versification
+}
 
-	book := &Book{}
-	verse := &Verse{}
-	chapter := &Chapter{}
-	books := make([]*Book, 0)
+// ParserVersion is the version of the verses Parser produces.
+const ParserVersion = 2
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 2)
+func (p *Parser) Version() int {
+	return ParserVersion
+}
 
-		// special case where the line has continued
-		if !strings.HasPrefix(parts[0], "\\") {
-			verse.Text = fmt.Sprintf("%s %s", verse.Text, strings.TrimSpace(line))
-			continue
-		}
+func (p *Parser) Diagnostics() diagnostic.List {
+	return p.diagnostics
+}
 
-		switch parts[0] {
-		case "\\id":
-			book = ParseBook(parts[1])
-			books = append(books, book)
-		case "\\c":
-			n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil {
-				fmt.Println(err)
-			}
-			chapter = &Chapter{
-				Number: n,
-			}
-			book.Chapters = append(book.Chapters, chapter)
-		case "\\v":
-			verse = ParseVerse(parts[1])
-			verse.Book = book.Name
-			verse.Chapter = chapter.Number
-			chapter.Verses = append(chapter.Verses, verse)
+// translationDirectories returns the subdirectories of location in name
+// order, or location itself when it has none.
+func translationDirectories(location string) ([]string, error) {
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return nil, err
+	}
+	directories := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			directories = append(directories, filepath.Join(location, entry.Name()))
 		}
 	}
-	return books
+	if len(directories) == 0 {
+		return []string{location}, nil
+	}
+	sort.Strings(directories)
+	return directories, nil
 }
 
-func loadBooksFromFiles() []*Book {
+func parseBooksFile(path string, syntax string, report *diagnostic.Reporter) ([]*Book, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	report.File = path
+	if syntax == "" {
+		syntax = detectSyntax(string(source))
+	}
+	parse, ok := syntaxes[syntax]
+	if !ok {
+		return nil, report.Errorf(0, 0, "unrecognized source syntax %q", syntax)
+	}
+	return parse(string(source), report)
+}
+
+func loadBooksFromFiles(directory string, syntax string, report *diagnostic.Reporter) ([]*Book, error) {
 	books := make([]*Book, 0)
-	directory := "texts/bible/nkjv/"
 	entries, err := os.ReadDir(directory)
 	if err != nil {
-		fmt.Println(err)
-		return books
+		return nil, err
 	}
 	for _, entry := range entries {
-		f, err := os.Open(directory + entry.Name())
-		if err != nil {
-			fmt.Printf("Error opening file %v\n", err)
+		if entry.IsDir() {
 			continue
 		}
-		defer f.Close()
-		books = append(books, parseBooksFile(f)...)
+		parsed, err := parseBooksFile(filepath.Join(directory, entry.Name()), syntax, report)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, parsed...)
 	}
-	return books
+	return books, nil
 }
 
 func generateVerseDocuments(books []*Book) []*Verse {
@@ -0,0 +1,57 @@
+package bible
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParser_Translations(t *testing.T) {
+	root := t.TempDir()
+	sources := map[string]string{
+		"kjv": "\\id JHN\n\\h John\n\\c 11\n\\v 35 Jesus wept.\n",
+		"web": "\\id JHN\n\\h John\n\\c 11\n\\v 35 Jesus wept.\n",
+	}
+	for translation, source := range sources {
+		assert.NoError(t, os.Mkdir(filepath.Join(root, translation), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, translation, "44-JHN.usfm"), []byte(source), 0644))
+	}
+
+	parser := &Parser{}
+	verses, err := parser.Parse(root)
+	assert.NoError(t, err)
+	assert.Empty(t, parser.Diagnostics())
+	assert.Len(t, verses, 2)
+	assert.Equal(t, "KJV", verses[0].Translation)
+	assert.Equal(t, "WEB", verses[1].Translation)
+
+	parser = &Parser{Translation: "AKJV"}
+	verses, err = parser.Parse(filepath.Join(root, "kjv"))
+	assert.NoError(t, err)
+	assert.Len(t, verses, 1)
+	assert.Equal(t, "AKJV", verses[0].Translation)
+	assert.Equal(t, "JHN", verses[0].BookID)
+}
+
+func TestParser_Versifications(t *testing.T) {
+	root := t.TempDir()
+	source := "\\id MAL\n\\h Malachi\n\\c 3\n\\v 24 And he shall turn the heart of the fathers to the children.\n"
+	for _, translation := range []string{"kjv", "wlc"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(root, translation), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, translation, "40-MAL.usfm"), []byte(source), 0644))
+	}
+
+	parser := &Parser{Versification: KJV, Versifications: map[string]*Versification{"WLC": Hebrew}}
+	_, err := parser.Parse(root)
+	assert.NoError(t, err)
+	outside := make([]string, 0)
+	for _, d := range parser.Diagnostics() {
+		if strings.Contains(d.Error(), "not in the") {
+			outside = append(outside, d.Error())
+		}
+	}
+	assert.Equal(t, []string{filepath.Join(root, "kjv") + ": warning: KJV MAL 3:24: not in the KJV versification"}, outside)
+}
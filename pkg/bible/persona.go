@@ -3,6 +3,7 @@ package bible
 import (
 	"context"
 	"fmt"
+	"strings"
 )
 
 type Persona struct{}
@@ -10,8 +11,18 @@ type Persona struct{}
 func (b *Persona) BuildCompletionContext(ctx context.Context, items []interface{}) (string, error) {
 	contextString := "Using the following verses for context to answer the question. Do not use other information or sources. \n context: "
 	for _, val := range items {
-		verse := val.(Verse)
-		contextString += fmt.Sprintf("%v %v:%v -- %v ", verse.Book, verse.Chapter, verse.Verse, verse.Text)
+		switch item := val.(type) {
+		case *Verse:
+			speaker := ""
+			if item.WordsOfJesus {
+				speaker = "(words of Jesus: " + strings.Join(item.JesusSpans, " ... ") + ") "
+			}
+			contextString += fmt.Sprintf("%v %v %v:%v -- %v %v", item.Translation, item.Book, item.Chapter, item.Verse, item.Text, speaker)
+		case *LexiconEntry:
+			contextString += lexiconContext(item)
+		default:
+			return "", fmt.Errorf("unexpected document type %T", val)
+		}
 	}
 	return contextString, nil
 }
@@ -19,3 +30,17 @@ func (b *Persona) BuildCompletionContext(ctx context.Context, items []interface{
 func (b *Persona) Prompt() string {
 	return "You are Jesus. You will respond in language like that of the NKJV bible as if you are Jesus talking to his son."
 }
+
+// lexiconContext describes how an original-language word is translated, so
+// that the verses pinned after it can be read with the word in mind.
+func lexiconContext(entry *LexiconEntry) string {
+	renderings := make([]string, 0, len(entry.Renderings))
+	for _, rendering := range entry.Renderings {
+		renderings = append(renderings, fmt.Sprintf("%s (%d)", rendering.Text, rendering.Count))
+	}
+	word := entry.Strong
+	if entry.Lemma != "" {
+		word = fmt.Sprintf("%s %s", entry.Strong, entry.Lemma)
+	}
+	return fmt.Sprintf("Strong's %s occurs %d times, translated as %s. ", word, entry.Occurrences, strings.Join(renderings, ", "))
+}
@@ -0,0 +1,533 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/usfm"
+	"strconv"
+	"strings"
+)
+
+// scope says where the text following a paragraph level marker belongs.
+type scope int
+
+const (
+	scopeIgnore scope = iota
+	scopeBookID
+	scopeBookName
+	scopeChapter
+	scopeHeading
+	scopeVerse
+)
+
+// ignoredMarkers introduce text that is not part of any verse, such as
+// identification, titles, introductions and parallel passage references.
+var ignoredMarkers = map[string]bool{
+	"ide": true, "sts": true, "rem": true, "usfm": true, "toc": true, "toca": true,
+	"mt": true, "mte": true, "imt": true, "imte": true, "is": true, "ip": true, "ipi": true,
+	"im": true, "imi": true, "ipq": true, "imq": true, "ipr": true, "iq": true, "ib": true,
+	"ili": true, "iot": true, "io": true, "iex": true, "ie": true, "cl": true, "cd": true,
+	"cp": true, "mr": true, "sr": true, "r": true, "periph": true, "lit": true,
+}
+
+// headingMarkers introduce section headings that are kept with the next verse.
+var headingMarkers = map[string]bool{
+	"s": true, "ms": true, "d": true, "sp": true, "qa": true,
+}
+
+// paragraphMarkers start a new paragraph of verse text.
+var paragraphMarkers = map[string]bool{
+	"p": true, "m": true, "po": true, "pr": true, "cls": true, "pmo": true, "pm": true,
+	"pmc": true, "pmr": true, "pi": true, "mi": true, "nb": true, "pc": true, "ph": true,
+	"lh": true, "li": true, "lf": true, "lim": true, "b": true, "tr": true,
+}
+
+// poetryMarkers start a new line of poetry.
+var poetryMarkers = map[string]bool{
+	"q": true, "qr": true, "qc": true, "qm": true, "qd": true,
+}
+
+// noteMarkers open footnotes, endnotes and cross references.
+var noteMarkers = map[string]bool{
+	"f": true, "fe": true, "ef": true, "x": true, "ex": true,
+}
+
+// skippedSpans are character markers whose content is not verse text.
+var skippedSpans = map[string]bool{
+	"fig": true, "rq": true, "va": true, "vp": true, "ca": true, "cat": true,
+}
+
+// usfmBuilder assembles books from a stream of USFM tokens.
+type usfmBuilder struct {
+	report  *diagnostic.Reporter
+	books   []*Book
+	book    *Book
+	chapter *Chapter
+	verse   *Verse
+	scope   scope
+	named   bool
+
+	// the \c or \v marker waiting for its number, and whether the current
+	// chapter was dropped because its number could not be read
+	numbered   usfm.Token
+	badChapter bool
+	lastVerse  int
+
+	// metadata waiting for the next verse
+	headings  []string
+	heading   strings.Builder
+	paragraph bool
+
+	// poetry state: the level of the line being written and whether a new
+	// line starts with the next piece of verse text
+	poetry    int
+	lineStart bool
+
+	note      *Note
+	noteKind  string
+	noteStart usfm.Token
+	noteText  *strings.Builder
+	reference strings.Builder
+	caller    strings.Builder
+	body      strings.Builder
+
+	skip      string
+	skipStart usfm.Token
+
+	// the \w span being read
+	word *Word
+
+	// whether a \wj span is open and its text in the current verse
+	jesus     bool
+	jesusText strings.Builder
+}
+
+// parseUSFM builds the books in a USFM document, reporting malformed markup
+// to report. It only returns an error when report is in strict mode.
+func parseUSFM(source string, report *diagnostic.Reporter) ([]*Book, error) {
+	builder := &usfmBuilder{report: report}
+	lexer := usfm.NewLexer(source)
+	for {
+		token, ok := lexer.Next()
+		if !ok {
+			break
+		}
+		if err := builder.handle(token); err != nil {
+			return nil, err
+		}
+	}
+	if err := builder.finish(); err != nil {
+		return nil, err
+	}
+	return builder.books, nil
+}
+
+func (b *usfmBuilder) handle(token usfm.Token) error {
+	if b.skip != "" {
+		if token.Kind == usfm.EndMarker && token.Name == b.skip {
+			b.skip = ""
+		}
+		return nil
+	}
+	if b.note != nil {
+		if token.Kind != usfm.Marker || !structuralMarker(token.Name) {
+			b.handleNote(token)
+			return nil
+		}
+		// A verse, chapter or book cannot start inside a note, so the note
+		// was never closed.
+		b.note = nil
+		if err := b.report.Errorf(b.noteStart.Line, b.noteStart.Column, "\\%s note is not closed with \\%s*", b.noteKind, b.noteKind); err != nil {
+			return err
+		}
+	}
+
+	switch token.Kind {
+	case usfm.Text:
+		return b.text(token)
+	case usfm.Attributes:
+		if b.word != nil {
+			b.wordAttributes(usfm.ParseAttributes(token.Text))
+		}
+	case usfm.EndMarker:
+		// Other character spans only affect the text they enclose.
+		switch token.Name {
+		case "w":
+			b.finishWord()
+		case "wj":
+			b.finishJesus()
+			b.jesus = false
+		}
+	case usfm.Marker:
+		return b.marker(token)
+	}
+	return nil
+}
+
+// finish closes the verse being built and reports anything left open at the
+// end of the document.
+func (b *usfmBuilder) finish() error {
+	if err := b.unnumbered(); err != nil {
+		return err
+	}
+	b.finishVerse()
+	if b.note != nil {
+		return b.report.Errorf(b.noteStart.Line, b.noteStart.Column, "\\%s note is not closed with \\%s*", b.noteKind, b.noteKind)
+	}
+	if b.skip != "" {
+		return b.report.Errorf(b.skipStart.Line, b.skipStart.Column, "\\%s is not closed with \\%s*", b.skip, b.skip)
+	}
+	return nil
+}
+
+// unnumbered reports a \c or \v marker that was not followed by a number.
+func (b *usfmBuilder) unnumbered() error {
+	switch {
+	case b.scope == scopeChapter:
+		b.scope = scopeIgnore
+		b.chapter = nil
+		b.badChapter = true
+		return b.report.Errorf(b.numbered.Line, b.numbered.Column, "\\c has no chapter number")
+	case b.verse != nil && b.verse.Verse < 0:
+		b.verse = nil
+		return b.report.Errorf(b.numbered.Line, b.numbered.Column, "\\v has no verse number")
+	}
+	return nil
+}
+
+func structuralMarker(name string) bool {
+	return name == "id" || name == "c" || name == "v"
+}
+
+func (b *usfmBuilder) marker(token usfm.Token) error {
+	if err := b.unnumbered(); err != nil {
+		return err
+	}
+	name, level := usfm.Split(token.Name)
+	switch {
+	case token.Name == "id":
+		b.finishVerse()
+		b.book = &Book{}
+		b.books = append(b.books, b.book)
+		b.chapter = nil
+		b.badChapter = false
+		b.named = false
+		b.jesus = false
+		b.scope = scopeBookID
+	case token.Name == "h" || token.Name == "toc2":
+		// Prefer the running header, falling back to the short table of
+		// contents entry, over the description on the \id line.
+		b.scope = scopeIgnore
+		if b.book != nil && !b.named {
+			b.book.Name = ""
+			b.named = true
+			b.scope = scopeBookName
+		}
+	case token.Name == "c":
+		b.finishVerse()
+		if b.book == nil {
+			b.scope = scopeIgnore
+			return b.report.Errorf(token.Line, token.Column, "\\c before \\id")
+		}
+		b.scope = scopeChapter
+		b.numbered = token
+	case token.Name == "v":
+		b.finishVerse()
+		b.scope = scopeVerse
+		if b.chapter == nil {
+			if b.badChapter {
+				// The chapter was already reported, so drop its verses quietly.
+				return nil
+			}
+			return b.report.Errorf(token.Line, token.Column, "\\v before the first \\c")
+		}
+		b.verse = &Verse{Verse: -1}
+		b.numbered = token
+	case token.Name == "w":
+		if b.verse != nil {
+			b.word = &Word{}
+		}
+	case token.Name == "wj":
+		b.jesus = true
+		b.jesusText.Reset()
+	case noteMarkers[token.Name]:
+		b.note = &Note{}
+		b.noteKind = token.Name
+		b.noteStart = token
+		b.noteText = &b.caller
+		b.caller.Reset()
+		b.reference.Reset()
+		b.body.Reset()
+	case skippedSpans[token.Name]:
+		b.skip = token.Name
+		b.skipStart = token
+	case headingMarkers[name]:
+		b.finishHeading()
+		b.scope = scopeHeading
+	case paragraphMarkers[name]:
+		b.finishHeading()
+		b.scope = scopeVerse
+		b.paragraph = true
+		b.poetry = 0
+		b.lineStart = false
+	case poetryMarkers[name]:
+		b.finishHeading()
+		b.scope = scopeVerse
+		b.poetry = level
+		b.lineStart = true
+	case ignoredMarkers[name] || strings.HasSuffix(name, "-s") || strings.HasSuffix(name, "-e"):
+		// Milestones such as \qt-s carry no text of their own.
+		if !strings.Contains(name, "-") {
+			b.finishHeading()
+			b.scope = scopeIgnore
+		}
+	}
+	return nil
+}
+
+func (b *usfmBuilder) text(token usfm.Token) error {
+	text := token.Text
+	switch b.scope {
+	case scopeBookID:
+		fields := strings.Fields(text)
+		if b.book != nil && len(fields) > 0 {
+			b.book.ID = fields[0]
+			if b.book.Name == "" {
+				b.book.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+			}
+		}
+		b.scope = scopeIgnore
+	case scopeBookName:
+		if b.book != nil {
+			b.book.Name = strings.TrimSpace(b.book.Name + " " + strings.TrimSpace(text))
+		}
+	case scopeChapter:
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return nil
+		}
+		b.scope = scopeIgnore
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n <= 0 {
+			b.chapter = nil
+			b.badChapter = true
+			return b.report.Errorf(token.Line, token.Column, "invalid chapter number %q", fields[0])
+		}
+		b.chapter = NewChapter(n)
+		b.badChapter = false
+		b.lastVerse = 0
+		b.book.Chapters = append(b.book.Chapters, b.chapter)
+	case scopeHeading:
+		b.heading.WriteString(text)
+	case scopeVerse:
+		return b.verseText(token)
+	}
+	return nil
+}
+
+func (b *usfmBuilder) verseText(token usfm.Token) error {
+	text := token.Text
+	if b.verse == nil || b.chapter == nil {
+		return nil
+	}
+	if b.verse.Verse < 0 {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		// The first word after \v is the verse number, possibly a range or
+		// segment such as 1-2 or 3a.
+		text = strings.TrimLeft(text, " \t\r\n")
+		end := strings.IndexFunc(text, func(r rune) bool { return r == ' ' || r == '\t' || r == '\r' || r == '\n' })
+		if end < 0 {
+			end = len(text)
+		}
+		number := text[:end]
+		digits := strings.IndexFunc(number, func(r rune) bool { return r < '0' || r > '9' })
+		if digits >= 0 {
+			number = number[:digits]
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil || n <= 0 {
+			b.verse = nil
+			return b.report.Errorf(token.Line, token.Column, "invalid verse number %q in chapter %d", text[:end], b.chapter.Number)
+		}
+		if n <= b.lastVerse {
+			b.report.Warnf(token.Line, token.Column, "verse %d of chapter %d follows verse %d", n, b.chapter.Number, b.lastVerse)
+		}
+		b.verse.Verse = n
+		b.lastVerse = lastVerse(text[:end], n)
+		b.startVerse()
+		text = text[end:]
+	}
+	if b.word != nil {
+		b.word.Text += text
+	}
+	if b.jesus {
+		b.jesusText.WriteString(text)
+	}
+	if strings.TrimSpace(text) == "" {
+		b.verse.Text += text
+		if b.poetry > 0 && !b.lineStart && len(b.verse.Lines) > 0 {
+			b.verse.Lines[len(b.verse.Lines)-1].Text += text
+		}
+		return nil
+	}
+	if b.poetry > 0 && (b.lineStart || len(b.verse.Lines) == 0) {
+		b.verse.Lines = append(b.verse.Lines, Line{Level: b.poetry})
+		b.lineStart = false
+	}
+	if b.poetry > 0 {
+		line := &b.verse.Lines[len(b.verse.Lines)-1]
+		line.Text += text
+	}
+	b.verse.Text += text
+	return nil
+}
+
+// wordAttributes records the Strong's numbers and lemma of the \w span being
+// read. OSIS style values such as "strong:H7225" are accepted as well.
+func (b *usfmBuilder) wordAttributes(attributes map[string]string) {
+	b.word.Strong = append(b.word.Strong, strongNumbers(attributes["strong"])...)
+	if lemma := attributes["lemma"]; lemma != "" {
+		b.word.Lemma = lemma
+	} else if lemma := attributes["default"]; lemma != "" {
+		b.word.Lemma = lemma
+	}
+}
+
+// finishWord keeps the \w span just closed when it carries any attributes.
+func (b *usfmBuilder) finishWord() {
+	word := b.word
+	b.word = nil
+	if word == nil || b.verse == nil || (len(word.Strong) == 0 && word.Lemma == "") {
+		return
+	}
+	word.Text = clean(word.Text)
+	b.verse.Words = append(b.verse.Words, *word)
+}
+
+// finishJesus keeps the text of the \wj span read so far in the current verse.
+func (b *usfmBuilder) finishJesus() {
+	text := clean(b.jesusText.String())
+	b.jesusText.Reset()
+	if b.verse == nil || text == "" {
+		return
+	}
+	b.verse.WordsOfJesus = true
+	b.verse.JesusSpans = append(b.verse.JesusSpans, text)
+}
+
+// lastVerse returns the final verse of a bridged number such as 1-3, or first
+// when number is a single verse.
+func lastVerse(number string, first int) int {
+	if dash := strings.IndexByte(number, '-'); dash >= 0 {
+		if last, err := strconv.Atoi(strings.TrimRight(number[dash+1:], "abcdefghijklmnopqrstuvwxyz")); err == nil && last > first {
+			return last
+		}
+	}
+	return first
+}
+
+// startVerse attaches the verse to its chapter along with any pending metadata.
+func (b *usfmBuilder) startVerse() {
+	b.finishHeading()
+	b.verse.Chapter = b.chapter.Number
+	if b.book != nil {
+		b.verse.BookID = b.book.ID
+		b.verse.Book = b.book.Name
+	}
+	b.verse.Paragraph = b.paragraph
+	b.verse.Headings = b.headings
+	b.paragraph = false
+	b.headings = nil
+	b.chapter.Verses = append(b.chapter.Verses, b.verse)
+}
+
+func (b *usfmBuilder) finishVerse() {
+	b.finishHeading()
+	if b.verse == nil {
+		return
+	}
+	b.verse.Text = clean(b.verse.Text)
+	for i := range b.verse.Lines {
+		b.verse.Lines[i].Text = clean(b.verse.Lines[i].Text)
+	}
+	b.verse.Strongs = verseStrongs(b.verse.Words)
+	// An open \wj span continues into the next verse.
+	b.finishJesus()
+	b.verse = nil
+	b.word = nil
+	if b.poetry > 0 {
+		// A line that runs on into the next verse continues there.
+		b.lineStart = true
+	}
+}
+
+// endVerse closes the current verse at an explicit end marker, such as an XML
+// verse milestone, so that any text before the next verse is ignored.
+func (b *usfmBuilder) endVerse() error {
+	if err := b.unnumbered(); err != nil {
+		return err
+	}
+	b.finishVerse()
+	if b.scope == scopeVerse {
+		b.scope = scopeIgnore
+	}
+	return nil
+}
+
+// endTitle closes an XML title element, returning to the verse it interrupted.
+func (b *usfmBuilder) endTitle() error {
+	b.finishHeading()
+	b.scope = scopeIgnore
+	if b.verse != nil {
+		b.scope = scopeVerse
+	}
+	return nil
+}
+
+func (b *usfmBuilder) finishHeading() {
+	if heading := clean(b.heading.String()); heading != "" {
+		b.headings = append(b.headings, heading)
+	}
+	b.heading.Reset()
+}
+
+func (b *usfmBuilder) handleNote(token usfm.Token) {
+	switch token.Kind {
+	case usfm.Text:
+		b.noteText.WriteString(token.Text)
+	case usfm.Marker:
+		switch token.Name {
+		case "fr", "xo":
+			b.noteText = &b.reference
+		default:
+			b.noteText = &b.body
+			b.body.WriteString(" ")
+		}
+	case usfm.EndMarker:
+		if token.Name != b.noteKind {
+			return
+		}
+		b.note.Caller = clean(b.caller.String())
+		b.note.Reference = clean(b.reference.String())
+		b.note.Text = clean(b.body.String())
+		if b.verse != nil {
+			if strings.HasPrefix(b.noteKind, "x") || b.noteKind == "ex" {
+				b.verse.CrossReferences = append(b.verse.CrossReferences, *b.note)
+			} else {
+				b.verse.Footnotes = append(b.verse.Footnotes, *b.note)
+			}
+		}
+		b.note = nil
+	}
+}
+
+// clean collapses runs of whitespace and removes the spaces that markers
+// leave before punctuation.
+func clean(text string) string {
+	text = strings.Join(strings.Fields(strings.ReplaceAll(text, "~", " ")), " ")
+	for _, punctuation := range []string{",", ".", ";", ":", "?", "!", ")"} {
+		text = strings.ReplaceAll(text, " "+punctuation, punctuation)
+	}
+	return text
+}
@@ -0,0 +1,106 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const psalm = `\id PSA - King James Version
+\h Psalms
+\toc1 The Book of Psalms
+\toc2 Psalms
+\mt1 The Book of Psalms
+\c 1
+\s1 The Way of the Righteous
+\q1
+\v 1 \w Blessed|strong="H835"\w* \w is|strong="H1980"\w* the man that walketh not in the counsel of the ungodly,
+\q2 nor standeth in the way of sinners,\f + \fr 1:1 \ft Or, \fq sinners\fq* \ft transgressors\f*
+\q2 nor sitteth in the seat of the scornful.
+\q1
+\v 2 But his delight \x - \xo 1:2 \xt Josh 1:8\x*is in the law of the \nd Lord\nd*;
+\b
+\p
+\v 3 And he shall be like a tree planted
+by the rivers of water.
+\c 2
+\v 1 Why do the heathen rage?
+`
+
+func TestParseUSFM(t *testing.T) {
+	books, err := parseUSFM(psalm, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	assert.Len(t, books, 1)
+	book := books[0]
+	assert.Equal(t, "PSA", book.ID)
+	assert.Equal(t, "Psalms", book.Name)
+	assert.Len(t, book.Chapters, 2)
+
+	verses := book.Chapters[0].Verses
+	assert.Len(t, verses, 3)
+
+	first := verses[0]
+	assert.Equal(t, 1, first.Verse)
+	assert.Equal(t, 1, first.Chapter)
+	assert.Equal(t, "Psalms", first.Book)
+	assert.Equal(t, "Blessed is the man that walketh not in the counsel of the ungodly, nor standeth in the way of sinners, nor sitteth in the seat of the scornful.", first.Text)
+	assert.Equal(t, []string{"The Way of the Righteous"}, first.Headings)
+	assert.Equal(t, []Line{
+		{Level: 1, Text: "Blessed is the man that walketh not in the counsel of the ungodly,"},
+		{Level: 2, Text: "nor standeth in the way of sinners,"},
+		{Level: 2, Text: "nor sitteth in the seat of the scornful."},
+	}, first.Lines)
+	assert.Equal(t, []Note{{Caller: "+", Reference: "1:1", Text: "Or, sinners transgressors"}}, first.Footnotes)
+
+	second := verses[1]
+	assert.Equal(t, "But his delight is in the law of the Lord;", second.Text)
+	assert.Equal(t, []Note{{Caller: "-", Reference: "1:2", Text: "Josh 1:8"}}, second.CrossReferences)
+	assert.Equal(t, []Line{{Level: 1, Text: "But his delight is in the law of the Lord;"}}, second.Lines)
+
+	third := verses[2]
+	assert.True(t, third.Paragraph)
+	assert.Empty(t, third.Lines)
+	assert.Equal(t, "And he shall be like a tree planted by the rivers of water.", third.Text)
+
+	assert.Equal(t, "Why do the heathen rage?", book.Chapters[1].Verses[0].Text)
+	assert.Equal(t, 2, book.Chapters[1].Verses[0].Chapter)
+}
+
+const malformed = `\c 1
+\id GEN
+\c 1
+\v 1 In the beginning\f + \ft unclosed note
+\v x Bad number.
+\v 2 And the earth.
+\c two
+\v 1 Dropped.
+\c 3
+\v 1 Kept.
+`
+
+func TestParseUSFM_Diagnostics(t *testing.T) {
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	report.File = "GEN.usfm"
+	books, err := parseUSFM(malformed, report)
+	assert.NoError(t, err)
+	assert.Len(t, books, 1)
+	assert.Len(t, books[0].Chapters, 2)
+	assert.Len(t, books[0].Chapters[0].Verses, 2)
+	assert.Equal(t, "In the beginning", books[0].Chapters[0].Verses[0].Text)
+	assert.Equal(t, "Kept.", books[0].Chapters[1].Verses[0].Text)
+
+	messages := make([]string, 0)
+	for _, d := range report.Diagnostics {
+		messages = append(messages, d.Error())
+	}
+	assert.Equal(t, []string{
+		`GEN.usfm:1:1: error: \c before \id`,
+		`GEN.usfm:4:22: error: \f note is not closed with \f*`,
+		`GEN.usfm:5:4: error: invalid verse number "x" in chapter 1`,
+		`GEN.usfm:7:4: error: invalid chapter number "two"`,
+	}, messages)
+
+	_, err = parseUSFM(malformed, diagnostic.NewReporter(diagnostic.Strict))
+	assert.EqualError(t, err, `1:1: error: \c before \id`)
+}
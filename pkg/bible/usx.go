@@ -0,0 +1,48 @@
+package bible
+
+import (
+	"encoding/xml"
+	"nvoke/pkg/diagnostic"
+)
+
+// parseUSX builds the books in a USX document. USX is an XML encoding of USFM,
+// so each element's style attribute is fed to the builder as the USFM marker
+// it stands for.
+func parseUSX(source string, report *diagnostic.Reporter) ([]*Book, error) {
+	reader := newXMLReader(source, report)
+	reader.element = func(start xml.StartElement) (func() error, error) {
+		return usxElement(reader, start)
+	}
+	return reader.read()
+}
+
+func usxElement(r *xmlReader, start xml.StartElement) (func() error, error) {
+	style := attribute(start, "style")
+	switch start.Name.Local {
+	case "book":
+		return nil, r.marker("id", attribute(start, "code")+" ")
+	case "chapter":
+		if attribute(start, "eid") != "" {
+			return nil, nil
+		}
+		return nil, r.marker("c", attribute(start, "number"))
+	case "verse":
+		if attribute(start, "eid") != "" {
+			return nil, r.builder.endVerse()
+		}
+		return nil, r.marker("v", attribute(start, "number")+" ")
+	case "para", "row":
+		return nil, r.marker(style, "")
+	case "note":
+		return r.closer(style), r.marker(style, attribute(start, "caller"))
+	case "char", "figure":
+		if style == "w" {
+			return r.word(attribute(start, "strong"), attribute(start, "lemma"))
+		}
+		if style == "" {
+			return nil, nil
+		}
+		return r.closer(style), r.marker(style, "")
+	}
+	return nil, nil
+}
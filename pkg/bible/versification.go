@@ -0,0 +1,379 @@
+package bible
+
+import (
+	"fmt"
+	"nvoke/pkg/diagnostic"
+	"sort"
+	"strings"
+)
+
+// Versification is a scheme for dividing the books into chapters and verses.
+// Translations disagree mostly where a verse starts a new chapter and in the
+// Psalm titles, so schemes other than KJV are described by how their numbering
+// differs from KJV's.
+type Versification struct {
+	Name string
+	// verses holds the verse count of each chapter by USFM book code.
+	verses map[string][]int
+	// mappings renumber KJV verse ranges into this scheme. References they
+	// do not cover are numbered the same in both schemes.
+	mappings []mapping
+}
+
+// mapping renumbers KJV verses first to last of chapter in book as the
+// consecutive verses starting at toVerse of toChapter.
+type mapping struct {
+	book      string
+	chapter   int
+	first     int
+	last      int
+	toChapter int
+	toVerse   int
+}
+
+// KJV is the versification of the King James Version, which most English
+// translations follow.
+var KJV = &Versification{Name: "KJV", verses: kjvVerses}
+
+// Hebrew is the versification of the Masoretic text, followed by Jewish and
+// many scholarly translations. It counts Psalm titles as verses and starts
+// several chapters a verse or more apart from KJV.
+var Hebrew = &Versification{Name: "Hebrew"}
+
+var versifications = map[string]*Versification{}
+
+func init() {
+	for _, rule := range hebrewMappings {
+		Hebrew.mappings = append(Hebrew.mappings, parseMapping(rule))
+	}
+	for psalm, shift := range hebrewPsalmTitles {
+		verses := kjvVerses["PSA"][psalm-1]
+		Hebrew.mappings = append(Hebrew.mappings, mapping{"PSA", psalm, 1, verses, psalm, 1 + shift})
+	}
+	Hebrew.verses = mappedVerses(Hebrew)
+	for _, v := range []*Versification{KJV, Hebrew} {
+		versifications[strings.ToLower(v.Name)] = v
+	}
+}
+
+// LookupVersification returns the scheme named name, ignoring case.
+func LookupVersification(name string) (*Versification, bool) {
+	v, ok := versifications[strings.ToLower(name)]
+	return v, ok
+}
+
+// Versifications returns the names of the known schemes in sorted order.
+func Versifications() []string {
+	names := make([]string, 0, len(versifications))
+	for _, v := range versifications {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chapters returns the number of chapters in book, or 0 when the scheme does
+// not number the book.
+func (v *Versification) Chapters(book string) int {
+	return len(v.verses[book])
+}
+
+// Verses returns the number of verses in chapter of book, or 0 when the
+// scheme has no such chapter.
+func (v *Versification) Verses(book string, chapter int) int {
+	chapters := v.verses[book]
+	if chapter < 1 || chapter > len(chapters) {
+		return 0
+	}
+	return chapters[chapter-1]
+}
+
+// Contains reports whether book chapter:verse exists in the scheme.
+func (v *Versification) Contains(book string, chapter int, verse int) bool {
+	return verse >= 1 && verse <= v.Verses(book, chapter)
+}
+
+// FromKJV returns the chapter and verse that number KJV's chapter:verse of
+// book in this scheme.
+func (v *Versification) FromKJV(book string, chapter int, verse int) (int, int) {
+	for _, m := range v.mappings {
+		if m.book == book && m.chapter == chapter && verse >= m.first && verse <= m.last {
+			return m.toChapter, m.toVerse + verse - m.first
+		}
+	}
+	return chapter, verse
+}
+
+// ToKJV returns the KJV chapter and verse of this scheme's chapter:verse of
+// book.
+func (v *Versification) ToKJV(book string, chapter int, verse int) (int, int) {
+	for _, m := range v.mappings {
+		if m.book == book && m.toChapter == chapter && verse >= m.toVerse && verse <= m.toVerse+m.last-m.first {
+			return m.chapter, m.first + verse - m.toVerse
+		}
+	}
+	return chapter, verse
+}
+
+// Map returns the chapter and verse that number this scheme's chapter:verse
+// of book in the scheme to.
+func (v *Versification) Map(to *Versification, book string, chapter int, verse int) (int, int) {
+	chapter, verse = v.ToKJV(book, chapter, verse)
+	return to.FromKJV(book, chapter, verse)
+}
+
+// Check reports as warnings the verses that are duplicated or fall outside
+// the scheme, and the chapters and verses of the scheme that are missing from
+// the books that verses cover. Books the scheme does not number, such as the
+// Apocrypha, are only checked for duplicates.
+func (v *Versification) Check(verses []*Verse, report *diagnostic.Reporter) {
+	type key struct {
+		translation string
+		book        string
+	}
+	books := make([]key, 0)
+	seen := make(map[key]map[[2]int]int)
+	for _, verse := range verses {
+		k := key{verse.Translation, verse.BookID}
+		if seen[k] == nil {
+			books = append(books, k)
+			seen[k] = make(map[[2]int]int)
+			if _, ok := LookupBook(verse.BookID); !ok {
+				report.Warnf(0, 0, "%s: unknown book", bookLabel(k.translation, k.book))
+			}
+		}
+		reference := [2]int{verse.Chapter, verse.Verse}
+		seen[k][reference]++
+		label := bookLabel(k.translation, k.book)
+		if seen[k][reference] == 2 {
+			report.Warnf(0, 0, "%s %d:%d: duplicate verse", label, verse.Chapter, verse.Verse)
+		}
+		if v.Chapters(k.book) > 0 && !v.Contains(k.book, verse.Chapter, verse.Verse) {
+			report.Warnf(0, 0, "%s %d:%d: not in the %s versification", label, verse.Chapter, verse.Verse, v.Name)
+		}
+	}
+	for _, k := range books {
+		label := bookLabel(k.translation, k.book)
+		for chapter := 1; chapter <= v.Chapters(k.book); chapter++ {
+			missing := make([]int, 0)
+			for verse := 1; verse <= v.Verses(k.book, chapter); verse++ {
+				if seen[k][[2]int{chapter, verse}] == 0 {
+					missing = append(missing, verse)
+				}
+			}
+			switch {
+			case len(missing) == 0:
+			case len(missing) == v.Verses(k.book, chapter):
+				report.Warnf(0, 0, "%s %d: missing chapter", label, chapter)
+			default:
+				report.Warnf(0, 0, "%s %d: missing %s %s", label, chapter,
+					plural(len(missing), "verse"), diagnostic.Ranges(missing))
+			}
+		}
+	}
+}
+
+func bookLabel(translation string, book string) string {
+	if translation == "" {
+		return book
+	}
+	return translation + " " + book
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
+
+// mappedVerses counts the verses of each chapter of v by renumbering every
+// KJV verse into it.
+func mappedVerses(v *Versification) map[string][]int {
+	counts := make(map[string][]int, len(kjvVerses))
+	for book, chapters := range kjvVerses {
+		for chapter, verses := range chapters {
+			for verse := 1; verse <= verses; verse++ {
+				c, n := v.FromKJV(book, chapter+1, verse)
+				for len(counts[book]) < c {
+					counts[book] = append(counts[book], 0)
+				}
+				counts[book][c-1] = max(counts[book][c-1], n)
+			}
+		}
+	}
+	return counts
+}
+
+// parseMapping parses a renumbering written as "BOOK chapter:first-last =
+// chapter:verse", such as "JOL 3:1-21 = 4:1".
+func parseMapping(rule string) mapping {
+	var m mapping
+	if _, err := fmt.Sscanf(rule, "%s %d:%d-%d = %d:%d", &m.book, &m.chapter, &m.first, &m.last, &m.toChapter, &m.toVerse); err == nil {
+		return m
+	}
+	if _, err := fmt.Sscanf(rule, "%s %d:%d = %d:%d", &m.book, &m.chapter, &m.first, &m.toChapter, &m.toVerse); err != nil {
+		panic(fmt.Sprintf("bible: invalid versification mapping %q: %v", rule, err))
+	}
+	m.last = m.first
+	return m
+}
+
+// hebrewMappings renumber KJV verses in the Hebrew scheme, apart from the
+// Psalm titles.
+var hebrewMappings = []string{
+	"GEN 31:55 = 32:1",
+	"GEN 32:1-32 = 32:2",
+	"EXO 8:1-4 = 7:26",
+	"EXO 8:5-32 = 8:1",
+	"EXO 22:1 = 21:37",
+	"EXO 22:2-31 = 22:1",
+	"LEV 6:1-7 = 5:20",
+	"LEV 6:8-30 = 6:1",
+	"NUM 16:36-50 = 17:1",
+	"NUM 17:1-13 = 17:16",
+	"NUM 29:40 = 30:1",
+	"NUM 30:1-16 = 30:2",
+	"DEU 12:32 = 13:1",
+	"DEU 13:1-18 = 13:2",
+	"DEU 22:30 = 23:1",
+	"DEU 23:1-25 = 23:2",
+	"DEU 29:1 = 28:69",
+	"DEU 29:2-29 = 29:1",
+	"1SA 23:29 = 24:1",
+	"1SA 24:1-22 = 24:2",
+	"2SA 18:33 = 19:1",
+	"2SA 19:1-43 = 19:2",
+	"1KI 4:21-34 = 5:1",
+	"1KI 5:1-18 = 5:15",
+	"2KI 11:21 = 12:1",
+	"2KI 12:1-21 = 12:2",
+	"1CH 6:1-15 = 5:27",
+	"1CH 6:16-81 = 6:1",
+	"2CH 2:1 = 1:18",
+	"2CH 2:2-18 = 2:1",
+	"2CH 14:1 = 13:23",
+	"2CH 14:2-15 = 14:1",
+	"NEH 4:1-6 = 3:33",
+	"NEH 4:7-23 = 4:1",
+	"NEH 9:38 = 10:1",
+	"NEH 10:1-39 = 10:2",
+	"JOB 41:1-8 = 40:25",
+	"JOB 41:9-34 = 41:1",
+	"ECC 5:1 = 4:17",
+	"ECC 5:2-20 = 5:1",
+	"SNG 6:13 = 7:1",
+	"SNG 7:1-13 = 7:2",
+	"ISA 9:1 = 8:23",
+	"ISA 9:2-21 = 9:1",
+	"ISA 64:1 = 63:19",
+	"ISA 64:2-12 = 64:1",
+	"JER 9:1 = 8:23",
+	"JER 9:2-26 = 9:1",
+	"EZK 20:45-49 = 21:1",
+	"EZK 21:1-32 = 21:6",
+	"DAN 4:1-3 = 3:31",
+	"DAN 4:4-37 = 4:1",
+	"DAN 5:31 = 6:1",
+	"DAN 6:1-28 = 6:2",
+	"HOS 1:10-11 = 2:1",
+	"HOS 2:1-23 = 2:3",
+	"HOS 11:12 = 12:1",
+	"HOS 12:1-14 = 12:2",
+	"HOS 13:16 = 14:1",
+	"HOS 14:1-9 = 14:2",
+	"JOL 2:28-32 = 3:1",
+	"JOL 3:1-21 = 4:1",
+	"JON 1:17 = 2:1",
+	"JON 2:1-10 = 2:2",
+	"MIC 5:1 = 4:14",
+	"MIC 5:2-15 = 5:1",
+	"NAM 1:15 = 2:1",
+	"NAM 2:1-13 = 2:2",
+	"ZEC 1:18-21 = 2:1",
+	"ZEC 2:1-13 = 2:5",
+	"MAL 4:1-6 = 3:19",
+}
+
+// hebrewPsalmTitles holds the number of verses the Hebrew scheme gives the
+// title of each Psalm whose title KJV leaves unnumbered.
+var hebrewPsalmTitles = map[int]int{
+	3: 1, 4: 1, 5: 1, 6: 1, 7: 1, 8: 1, 9: 1, 12: 1, 18: 1, 19: 1, 20: 1,
+	21: 1, 22: 1, 30: 1, 31: 1, 34: 1, 36: 1, 38: 1, 39: 1, 40: 1, 41: 1,
+	42: 1, 44: 1, 45: 1, 46: 1, 47: 1, 48: 1, 49: 1, 51: 2, 52: 2, 53: 1,
+	54: 2, 55: 1, 56: 1, 57: 1, 58: 1, 59: 1, 60: 2, 61: 1, 62: 1, 63: 1,
+	64: 1, 65: 1, 67: 1, 68: 1, 69: 1, 70: 1, 75: 1, 76: 1, 77: 1, 80: 1,
+	81: 1, 83: 1, 84: 1, 85: 1, 88: 1, 89: 1, 92: 1, 102: 1, 108: 1, 140: 1,
+	142: 1,
+}
+
+// kjvVerses holds the verse count of each chapter of the books of the
+// Protestant canon in the KJV.
+var kjvVerses = map[string][]int{
+	"GEN": {31, 25, 24, 26, 32, 22, 24, 22, 29, 32, 32, 20, 18, 24, 21, 16, 27, 33, 38, 18, 34, 24, 20, 67, 34, 35, 46, 22, 35, 43, 55, 32, 20, 31, 29, 43, 36, 30, 23, 23, 57, 38, 34, 34, 28, 34, 31, 22, 33, 26},
+	"EXO": {22, 25, 22, 31, 23, 30, 25, 32, 35, 29, 10, 51, 22, 31, 27, 36, 16, 27, 25, 26, 36, 31, 33, 18, 40, 37, 21, 43, 46, 38, 18, 35, 23, 35, 35, 38, 29, 31, 43, 38},
+	"LEV": {17, 16, 17, 35, 19, 30, 38, 36, 24, 20, 47, 8, 59, 57, 33, 34, 16, 30, 37, 27, 24, 33, 44, 23, 55, 46, 34},
+	"NUM": {54, 34, 51, 49, 31, 27, 89, 26, 23, 36, 35, 16, 33, 45, 41, 50, 13, 32, 22, 29, 35, 41, 30, 25, 18, 65, 23, 31, 40, 16, 54, 42, 56, 29, 34, 13},
+	"DEU": {46, 37, 29, 49, 33, 25, 26, 20, 29, 22, 32, 32, 18, 29, 23, 22, 20, 22, 21, 20, 23, 30, 25, 22, 19, 19, 26, 68, 29, 20, 30, 52, 29, 12},
+	"JOS": {18, 24, 17, 24, 15, 27, 26, 35, 27, 43, 23, 24, 33, 15, 63, 10, 18, 28, 51, 9, 45, 34, 16, 33},
+	"JDG": {36, 23, 31, 24, 31, 40, 25, 35, 57, 18, 40, 15, 25, 20, 20, 31, 13, 31, 30, 48, 25},
+	"RUT": {22, 23, 18, 22},
+	"1SA": {28, 36, 21, 22, 12, 21, 17, 22, 27, 27, 15, 25, 23, 52, 35, 23, 58, 30, 24, 42, 15, 23, 29, 22, 44, 25, 12, 25, 11, 31, 13},
+	"2SA": {27, 32, 39, 12, 25, 23, 29, 18, 13, 19, 27, 31, 39, 33, 37, 23, 29, 33, 43, 26, 22, 51, 39, 25},
+	"1KI": {53, 46, 28, 34, 18, 38, 51, 66, 28, 29, 43, 33, 34, 31, 34, 34, 24, 46, 21, 43, 29, 53},
+	"2KI": {18, 25, 27, 44, 27, 33, 20, 29, 37, 36, 21, 21, 25, 29, 38, 20, 41, 37, 37, 21, 26, 20, 37, 20, 30},
+	"1CH": {54, 55, 24, 43, 26, 81, 40, 40, 44, 14, 47, 40, 14, 17, 29, 43, 27, 17, 19, 8, 30, 19, 32, 31, 31, 32, 34, 21, 30},
+	"2CH": {17, 18, 17, 22, 14, 42, 22, 18, 31, 19, 23, 16, 22, 15, 19, 14, 19, 34, 11, 37, 20, 12, 21, 27, 28, 23, 9, 27, 36, 27, 21, 33, 25, 33, 27, 23},
+	"EZR": {11, 70, 13, 24, 17, 22, 28, 36, 15, 44},
+	"NEH": {11, 20, 32, 23, 19, 19, 73, 18, 38, 39, 36, 47, 31},
+	"EST": {22, 23, 15, 17, 14, 14, 10, 17, 32, 3},
+	"JOB": {22, 13, 26, 21, 27, 30, 21, 22, 35, 22, 20, 25, 28, 22, 35, 22, 16, 21, 29, 29, 34, 30, 17, 25, 6, 14, 23, 28, 25, 31, 40, 22, 33, 37, 16, 33, 24, 41, 30, 24, 34, 17},
+	"PSA": {6, 12, 8, 8, 12, 10, 17, 9, 20, 18, 7, 8, 6, 7, 5, 11, 15, 50, 14, 9, 13, 31, 6, 10, 22, 12, 14, 9, 11, 12, 24, 11, 22, 22, 28, 12, 40, 22, 13, 17, 13, 11, 5, 26, 17, 11, 9, 14, 20, 23, 19, 9, 6, 7, 23, 13, 11, 11, 17, 12, 8, 12, 11, 10, 13, 20, 7, 35, 36, 5, 24, 20, 28, 23, 10, 12, 20, 72, 13, 19, 16, 8, 18, 12, 13, 17, 7, 18, 52, 17, 16, 15, 5, 23, 11, 13, 12, 9, 9, 5, 8, 28, 22, 35, 45, 48, 43, 13, 31, 7, 10, 10, 9, 8, 18, 19, 2, 29, 176, 7, 8, 9, 4, 8, 5, 6, 5, 6, 8, 8, 3, 18, 3, 3, 21, 26, 9, 8, 24, 13, 10, 7, 12, 15, 21, 10, 20, 14, 9, 6},
+	"PRO": {33, 22, 35, 27, 23, 35, 27, 36, 18, 32, 31, 28, 25, 35, 33, 33, 28, 24, 29, 30, 31, 29, 35, 34, 28, 28, 27, 28, 27, 33, 31},
+	"ECC": {18, 26, 22, 16, 20, 12, 29, 17, 18, 20, 10, 14},
+	"SNG": {17, 17, 11, 16, 16, 13, 13, 14},
+	"ISA": {31, 22, 26, 6, 30, 13, 25, 22, 21, 34, 16, 6, 22, 32, 9, 14, 14, 7, 25, 6, 17, 25, 18, 23, 12, 21, 13, 29, 24, 33, 9, 20, 24, 17, 10, 22, 38, 22, 8, 31, 29, 25, 28, 28, 25, 13, 15, 22, 26, 11, 23, 15, 12, 17, 13, 12, 21, 14, 21, 22, 11, 12, 19, 12, 25, 24},
+	"JER": {19, 37, 25, 31, 31, 30, 34, 22, 26, 25, 23, 17, 27, 22, 21, 21, 27, 23, 15, 18, 14, 30, 40, 10, 38, 24, 22, 17, 32, 24, 40, 44, 26, 22, 19, 32, 21, 28, 18, 16, 18, 22, 13, 30, 5, 28, 7, 47, 39, 46, 64, 34},
+	"LAM": {22, 22, 66, 22, 22},
+	"EZK": {28, 10, 27, 17, 17, 14, 27, 18, 11, 22, 25, 28, 23, 23, 8, 63, 24, 32, 14, 49, 32, 31, 49, 27, 17, 21, 36, 26, 21, 26, 18, 32, 33, 31, 15, 38, 28, 23, 29, 49, 26, 20, 27, 31, 25, 24, 23, 35},
+	"DAN": {21, 49, 30, 37, 31, 28, 28, 27, 27, 21, 45, 13},
+	"HOS": {11, 23, 5, 19, 15, 11, 16, 14, 17, 15, 12, 14, 16, 9},
+	"JOL": {20, 32, 21},
+	"AMO": {15, 16, 15, 13, 27, 14, 17, 14, 15},
+	"OBA": {21},
+	"JON": {17, 10, 10, 11},
+	"MIC": {16, 13, 12, 13, 15, 16, 20},
+	"NAM": {15, 13, 19},
+	"HAB": {17, 20, 19},
+	"ZEP": {18, 15, 20},
+	"HAG": {15, 23},
+	"ZEC": {21, 13, 10, 14, 11, 15, 14, 23, 17, 12, 17, 14, 9, 21},
+	"MAL": {14, 17, 18, 6},
+	"MAT": {25, 23, 17, 25, 48, 34, 29, 34, 38, 42, 30, 50, 58, 36, 39, 28, 27, 35, 30, 34, 46, 46, 39, 51, 46, 75, 66, 20},
+	"MRK": {45, 28, 35, 41, 43, 56, 37, 38, 50, 52, 33, 44, 37, 72, 47, 20},
+	"LUK": {80, 52, 38, 44, 39, 49, 50, 56, 62, 42, 54, 59, 35, 35, 32, 31, 37, 43, 48, 47, 38, 71, 56, 53},
+	"JHN": {51, 25, 36, 54, 47, 71, 53, 59, 41, 42, 57, 50, 38, 31, 27, 33, 26, 40, 42, 31, 25},
+	"ACT": {26, 47, 26, 37, 42, 15, 60, 40, 43, 48, 30, 25, 52, 28, 41, 40, 34, 28, 41, 38, 40, 30, 35, 27, 27, 32, 44, 31},
+	"ROM": {32, 29, 31, 25, 21, 23, 25, 39, 33, 21, 36, 21, 14, 23, 33, 27},
+	"1CO": {31, 16, 23, 21, 13, 20, 40, 13, 27, 33, 34, 31, 13, 40, 58, 24},
+	"2CO": {24, 17, 18, 18, 21, 18, 16, 24, 15, 18, 33, 21, 14},
+	"GAL": {24, 21, 29, 31, 26, 18},
+	"EPH": {23, 22, 21, 32, 33, 24},
+	"PHP": {30, 30, 21, 23},
+	"COL": {29, 23, 25, 18},
+	"1TH": {10, 20, 13, 18, 28},
+	"2TH": {12, 17, 18},
+	"1TI": {20, 15, 16, 16, 25, 21},
+	"2TI": {18, 26, 17, 22},
+	"TIT": {16, 15, 15},
+	"PHM": {25},
+	"HEB": {14, 18, 19, 16, 14, 20, 28, 13, 28, 39, 40, 29, 25},
+	"JAS": {27, 26, 18, 17, 20},
+	"1PE": {25, 25, 22, 19, 14},
+	"2PE": {21, 22, 18},
+	"1JN": {10, 29, 24, 21, 21},
+	"2JN": {13},
+	"3JN": {14},
+	"JUD": {25},
+	"REV": {20, 29, 22, 11, 14, 17, 17, 13, 21, 11, 19, 17, 18, 20, 8, 21, 18, 24, 21, 15, 27, 21},
+}
@@ -0,0 +1,100 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersification_Counts(t *testing.T) {
+	chapters, verses := 0, 0
+	for book := range kjvVerses {
+		for chapter := 1; chapter <= KJV.Chapters(book); chapter++ {
+			chapters++
+			verses += KJV.Verses(book, chapter)
+		}
+	}
+	assert.Equal(t, 1189, chapters)
+	assert.Equal(t, 31102, verses)
+
+	assert.Equal(t, 4, KJV.Chapters("MAL"))
+	assert.Equal(t, 3, Hebrew.Chapters("MAL"))
+	assert.Equal(t, 24, Hebrew.Verses("MAL", 3))
+	assert.Equal(t, 4, Hebrew.Chapters("JOL"))
+	assert.Equal(t, 9, Hebrew.Verses("PSA", 3))
+	assert.True(t, KJV.Contains("JHN", 3, 16))
+	assert.False(t, KJV.Contains("JHN", 3, 37))
+	assert.False(t, KJV.Contains("TOB", 1, 1))
+}
+
+func TestVersification_Map(t *testing.T) {
+	chapter, verse := KJV.Map(Hebrew, "MAL", 4, 5)
+	assert.Equal(t, []int{3, 23}, []int{chapter, verse})
+	chapter, verse = Hebrew.Map(KJV, "JOL", 3, 1)
+	assert.Equal(t, []int{2, 28}, []int{chapter, verse})
+	chapter, verse = KJV.Map(Hebrew, "PSA", 51, 1)
+	assert.Equal(t, []int{51, 3}, []int{chapter, verse})
+	chapter, verse = KJV.Map(Hebrew, "JHN", 3, 16)
+	assert.Equal(t, []int{3, 16}, []int{chapter, verse})
+
+	for book, chapters := range kjvVerses {
+		for c, verses := range chapters {
+			for v := 1; v <= verses; v++ {
+				chapter, verse := KJV.Map(Hebrew, book, c+1, v)
+				assert.True(t, Hebrew.Contains(book, chapter, verse), "%s %d:%d", book, c+1, v)
+			}
+		}
+	}
+}
+
+func TestVersification_Check(t *testing.T) {
+	verses := make([]*Verse, 0)
+	for verse := 1; verse <= 25; verse++ {
+		if verse == 4 || verse == 8 || verse == 9 {
+			continue
+		}
+		verses = append(verses, &Verse{Translation: "KJV", BookID: "JUD", Chapter: 1, Verse: verse})
+	}
+	verses = append(verses,
+		&Verse{Translation: "KJV", BookID: "JUD", Chapter: 1, Verse: 3},
+		&Verse{Translation: "KJV", BookID: "JUD", Chapter: 1, Verse: 26},
+		&Verse{Translation: "KJV", BookID: "XYZ", Chapter: 1, Verse: 1},
+	)
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	KJV.Check(verses, report)
+	messages := make([]string, 0)
+	for _, d := range report.Diagnostics {
+		messages = append(messages, d.Error())
+	}
+	assert.Equal(t, []string{
+		"warning: KJV JUD 1:3: duplicate verse",
+		"warning: KJV JUD 1:26: not in the KJV versification",
+		"warning: KJV XYZ: unknown book",
+		"warning: KJV JUD 1: missing verses 4, 8-9",
+	}, messages)
+}
+
+func TestSortVerses(t *testing.T) {
+	verses := []*Verse{
+		{BookID: "JHN", Chapter: 3, Verse: 16},
+		{BookID: "GEN", Chapter: 1, Verse: 2},
+		{BookID: "TOB", Chapter: 1, Verse: 1},
+		{BookID: "GEN", Chapter: 1, Verse: 1},
+	}
+	SortVerses(verses)
+	order := make([]string, 0)
+	for _, verse := range verses {
+		order = append(order, verse.BookID)
+	}
+	assert.Equal(t, []string{"GEN", "GEN", "TOB", "JHN"}, order)
+	assert.Equal(t, 1, verses[0].Verse)
+
+	books, ok := BookRange("Matthew", "Jn")
+	assert.True(t, ok)
+	assert.Len(t, books, 4)
+	assert.Equal(t, NewTestament, books[0].Testament)
+	assert.Len(t, TestamentBooks(OldTestament), 39)
+	_, ok = BookRange("John", "Matthew")
+	assert.False(t, ok)
+}
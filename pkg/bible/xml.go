@@ -0,0 +1,144 @@
+package bible
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"nvoke/pkg/diagnostic"
+	"nvoke/pkg/usfm"
+	"strings"
+)
+
+// xmlReader walks an XML Bible and feeds the equivalent USFM tokens to a
+// usfmBuilder, so OSIS and USX documents produce the same model as USFM.
+// element is called for every start element and returns the function to call
+// when the element ends, if any.
+type xmlReader struct {
+	decoder *xml.Decoder
+	builder *usfmBuilder
+	element func(start xml.StartElement) (func() error, error)
+	// quotes holds the sIDs of open OSIS quote milestones spoken by Jesus.
+	quotes map[string]bool
+}
+
+func newXMLReader(source string, report *diagnostic.Reporter) *xmlReader {
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = false
+	return &xmlReader{decoder: decoder, builder: &usfmBuilder{report: report}, quotes: make(map[string]bool)}
+}
+
+func (r *xmlReader) read() ([]*Book, error) {
+	ends := make([]func() error, 0)
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, column := r.decoder.InputPos()
+			if err := r.builder.report.Errorf(line, column, "invalid XML: %v", err); err != nil {
+				return nil, err
+			}
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			end, err := r.element(token)
+			if err != nil {
+				return nil, err
+			}
+			ends = append(ends, end)
+		case xml.EndElement:
+			if len(ends) == 0 {
+				continue
+			}
+			end := ends[len(ends)-1]
+			ends = ends[:len(ends)-1]
+			if end != nil {
+				if err := end(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.CharData:
+			if err := r.emit(usfm.Text, "", string(token)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := r.builder.finish(); err != nil {
+		return nil, err
+	}
+	return r.builder.books, nil
+}
+
+// emit passes a token positioned at the decoder's current offset to the builder.
+func (r *xmlReader) emit(kind usfm.Kind, name string, text string) error {
+	line, column := r.decoder.InputPos()
+	return r.builder.handle(usfm.Token{Kind: kind, Name: name, Text: text, Line: line, Column: column})
+}
+
+// marker emits a marker followed by its text, as in "\c 1".
+func (r *xmlReader) marker(name string, text string) error {
+	if err := r.emit(usfm.Marker, name, ""); err != nil {
+		return err
+	}
+	if text == "" {
+		return nil
+	}
+	return r.emit(usfm.Text, "", text)
+}
+
+// closer returns an end function that emits the end marker for name.
+func (r *xmlReader) closer(name string) func() error {
+	return func() error {
+		return r.emit(usfm.EndMarker, name, "")
+	}
+}
+
+// word emits a \w span for a marked word. The returned end function emits its
+// attributes and end marker.
+func (r *xmlReader) word(strong string, lemma string) (func() error, error) {
+	if err := r.emit(usfm.Marker, "w", ""); err != nil {
+		return nil, err
+	}
+	return func() error {
+		attributes := make([]string, 0, 2)
+		if strong != "" {
+			attributes = append(attributes, fmt.Sprintf("strong=%q", strong))
+		}
+		if lemma != "" {
+			attributes = append(attributes, fmt.Sprintf("lemma=%q", lemma))
+		}
+		if len(attributes) > 0 {
+			if err := r.emit(usfm.Attributes, "", strings.Join(attributes, " ")); err != nil {
+				return err
+			}
+		}
+		return r.emit(usfm.EndMarker, "w", "")
+	}, nil
+}
+
+func attribute(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// detectSyntax guesses the format of a Bible source file from its root element.
+func detectSyntax(source string) string {
+	trimmed := strings.TrimLeft(strings.TrimPrefix(source, "\ufeff"), " \t\r\n")
+	if !strings.HasPrefix(trimmed, "<") {
+		return SyntaxUSFM
+	}
+	head := trimmed[:min(len(trimmed), 1024)]
+	switch {
+	case strings.Contains(head, "<osis"):
+		return SyntaxOSIS
+	case strings.Contains(head, "<usx"):
+		return SyntaxUSX
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package bible
+
+import (
+	"nvoke/pkg/diagnostic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const osis = `<?xml version="1.0" encoding="UTF-8"?>
+<osis xmlns="http://www.bibletechnologies.net/2003/OSIS/namespace">
+<osisText osisIDWork="KJV">
+<header><work osisWork="KJV"><title>King James Version</title></work></header>
+<div type="book" osisID="John">
+<title type="main">The Gospel According to St. John</title>
+<chapter sID="John.1" osisID="John.1"/>
+<title type="section">The Word Made Flesh</title>
+<p>
+<verse sID="John.1.1" osisID="John.1.1"/>In the beginning was <w lemma="strong:G3056">the Word</w>, and the Word was with God<note type="crossReference" osisRef="John.1.1"><reference osisRef="Gen.1.1">Gen 1:1</reference></note>.<verse eID="John.1.1"/>
+</p>
+<p>
+<verse sID="John.1.2" osisID="John.1.2"/>The same was in the beginning
+</p>
+<lg><l level="1">with God.</l></lg>
+<verse eID="John.1.2"/>
+<chapter eID="John.1"/>
+<chapter osisID="John.2">
+<verse osisID="John.2.1 John.2.2">And the third day there was a marriage.</verse>
+</chapter>
+</div>
+</osisText>
+</osis>`
+
+func TestParseOSIS(t *testing.T) {
+	books, err := parseOSIS(osis, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	assert.Len(t, books, 1)
+	assert.Equal(t, "JHN", books[0].ID)
+	assert.Equal(t, "John", books[0].Name)
+	assert.Len(t, books[0].Chapters, 2)
+
+	verses := books[0].Chapters[0].Verses
+	assert.Len(t, verses, 2)
+	assert.Equal(t, "In the beginning was the Word, and the Word was with God.", verses[0].Text)
+	assert.Equal(t, []string{"The Word Made Flesh"}, verses[0].Headings)
+	assert.Equal(t, []Note{{Caller: "+", Reference: "1:1", Text: "Gen 1:1"}}, verses[0].CrossReferences)
+	assert.Equal(t, []Word{{Text: "the Word", Strong: []string{"G3056"}}}, verses[0].Words)
+	assert.Equal(t, "The same was in the beginning with God.", verses[1].Text)
+	assert.Equal(t, []Line{{Level: 1, Text: "with God."}}, verses[1].Lines)
+
+	bridged := books[0].Chapters[1].Verses[0]
+	assert.Equal(t, 1, bridged.Verse)
+	assert.Equal(t, "And the third day there was a marriage.", bridged.Text)
+}
+
+const usx = `<?xml version="1.0" encoding="utf-8"?>
+<usx version="3.0">
+  <book code="JHN" style="id">- King James Version</book>
+  <para style="h">John</para>
+  <chapter number="11" style="c" sid="JHN 11" />
+  <para style="s1">Jesus Weeps</para>
+  <para style="p">
+    <verse number="35" style="v" sid="JHN 11:35" />Jesus <char style="wj">wept</char>.<note caller="+" style="f"><char style="fr">11:35 </char><char style="ft">The shortest verse.</char></note><verse eid="JHN 11:35" />
+    <verse number="36" style="v" sid="JHN 11:36" />Then said the Jews, Behold how he loved him!<verse eid="JHN 11:36" />
+  </para>
+  <chapter eid="JHN 11" />
+</usx>`
+
+func TestParseUSX(t *testing.T) {
+	books, err := parseUSX(usx, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	assert.Len(t, books, 1)
+	assert.Equal(t, "JHN", books[0].ID)
+	assert.Equal(t, "John", books[0].Name)
+
+	verses := books[0].Chapters[0].Verses
+	assert.Len(t, verses, 2)
+	assert.Equal(t, 35, verses[0].Verse)
+	assert.Equal(t, 11, verses[0].Chapter)
+	assert.Equal(t, "Jesus wept.", verses[0].Text)
+	assert.Equal(t, []string{"wept"}, verses[0].JesusSpans)
+	assert.True(t, verses[0].Paragraph)
+	assert.Equal(t, []string{"Jesus Weeps"}, verses[0].Headings)
+	assert.Equal(t, []Note{{Caller: "+", Reference: "11:35", Text: "The shortest verse."}}, verses[0].Footnotes)
+	assert.Equal(t, "Then said the Jews, Behold how he loved him!", verses[1].Text)
+}
+
+func TestParseOSIS_WordsOfJesus(t *testing.T) {
+	source := `<osis><osisText><div type="book" osisID="John"><chapter osisID="John.14">
+<verse osisID="John.14.5">Thomas saith unto him, Lord, we know not whither thou goest.</verse>
+<verse osisID="John.14.6">Jesus saith unto him, <q who="Jesus" sID="q1"/>I am the way, the truth, and the life:</verse>
+<verse osisID="John.14.7">If ye had known me<q eID="q1"/>.</verse>
+</chapter></div></osisText></osis>`
+	books, err := parseOSIS(source, diagnostic.NewReporter(diagnostic.Strict))
+	assert.NoError(t, err)
+	verses := books[0].Chapters[0].Verses
+	assert.False(t, verses[0].WordsOfJesus)
+	assert.Equal(t, []string{"I am the way, the truth, and the life:"}, verses[1].JesusSpans)
+	assert.Equal(t, []string{"If ye had known me"}, verses[2].JesusSpans)
+}
+
+func TestDetectSyntax(t *testing.T) {
+	assert.Equal(t, SyntaxOSIS, detectSyntax(osis))
+	assert.Equal(t, SyntaxUSX, detectSyntax(usx))
+	assert.Equal(t, SyntaxUSFM, detectSyntax(psalm))
+}
@@ -0,0 +1,159 @@
+// Package diagnostic records problems found while parsing source texts along
+// with the file, line and column they were found at.
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity says whether a diagnostic stops the parse in strict mode.
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Mode controls how a Reporter treats errors.
+type Mode int
+
+const (
+	// Lenient records errors, skips the offending element and keeps parsing.
+	Lenient Mode = iota
+	// Strict stops at the first error.
+	Strict
+)
+
+// Diagnostic is a single problem in a source file. Line and Column are one
+// based, and zero when the problem is not tied to a position.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) Error() string {
+	location := d.File
+	if d.Line > 0 {
+		location = strings.TrimPrefix(fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column), ":")
+	}
+	if location == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, d.Severity, d.Message)
+}
+
+// List is the set of diagnostics produced by a parse.
+type List []Diagnostic
+
+func (l List) Error() string {
+	errors := l.Errors()
+	switch len(errors) {
+	case 0:
+		return "no errors"
+	case 1:
+		return errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", errors[0].Error(), len(errors)-1)
+}
+
+// Errors returns the diagnostics with Error severity.
+func (l List) Errors() List {
+	return l.filter(Error)
+}
+
+// Warnings returns the diagnostics with Warning severity.
+func (l List) Warnings() List {
+	return l.filter(Warning)
+}
+
+func (l List) filter(severity Severity) List {
+	filtered := make(List, 0)
+	for _, d := range l {
+		if d.Severity == severity {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// Summary describes the number of errors and warnings in the list.
+func (l List) Summary() string {
+	return fmt.Sprintf("%s, %s", plural(len(l.Errors()), "error"), plural(len(l.Warnings()), "warning"))
+}
+
+// Ranges formats ascending numbers as a list of ranges, such as "4, 8-9", for
+// messages about missing chapters or verses.
+func Ranges(numbers []int) string {
+	ranges := make([]string, 0)
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprint(numbers[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Reporter collects the diagnostics for one parse. File is attached to every
+// diagnostic and should be set before each source file is read.
+type Reporter struct {
+	Mode        Mode
+	File        string
+	Diagnostics List
+}
+
+func NewReporter(mode Mode) *Reporter {
+	return &Reporter{Mode: mode, Diagnostics: make(List, 0)}
+}
+
+// Warnf records a warning. Warnings never stop a parse.
+func (r *Reporter) Warnf(line int, column int, format string, args ...interface{}) {
+	r.report(Warning, line, column, format, args...)
+}
+
+// Errorf records an error. In strict mode the error is returned and the caller
+// should stop parsing; in lenient mode it returns nil and the caller should
+// skip the offending element.
+func (r *Reporter) Errorf(line int, column int, format string, args ...interface{}) error {
+	d := r.report(Error, line, column, format, args...)
+	if r.Mode == Strict {
+		return d
+	}
+	return nil
+}
+
+func (r *Reporter) report(severity Severity, line int, column int, format string, args ...interface{}) Diagnostic {
+	d := Diagnostic{
+		File:     r.File,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Message:  strings.TrimSpace(fmt.Sprintf(format, args...)),
+	}
+	r.Diagnostics = append(r.Diagnostics, d)
+	return d
+}
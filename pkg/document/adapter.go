@@ -0,0 +1,30 @@
+package document
+
+import "nvoke/pkg/embedding"
+
+type EmbeddingAdapter struct{}
+
+func NewEmbeddingAdapter() embedding.Adapter[*Document] {
+	return &EmbeddingAdapter{}
+}
+
+// GetContent embeds the chunk with its title and headings, which often say
+// more about what a passage is about than the passage itself.
+func (a *EmbeddingAdapter) GetContent(document *Document) string {
+	if path := document.Path(); path != "" {
+		return path + "\n\n" + document.Text
+	}
+	return document.Text
+}
+
+func (a *EmbeddingAdapter) StoreEmbedding(document *Document, embedding []float32) {
+	document.Embedding = embedding
+}
+
+func (a *EmbeddingAdapter) GetID(document *Document) string {
+	return document.ID
+}
+
+func (a *EmbeddingAdapter) GetEmbedding(document *Document) []float32 {
+	return document.Embedding
+}
@@ -0,0 +1,36 @@
+package document
+
+import "regexp"
+
+// Default chunk sizes, in tokens.
+const (
+	DefaultChunkTokens  = 300
+	DefaultChunkOverlap = 50
+)
+
+// tokenPattern counts words and punctuation marks as tokens, which comes
+// close to model tokens for prose without needing the model's vocabulary.
+var tokenPattern = regexp.MustCompile(`[\p{L}\p{N}]+|[^\p{L}\p{N}\s]`)
+
+// CountTokens returns the approximate number of model tokens in text.
+func CountTokens(text string) int {
+	return len(tokenPattern.FindAllStringIndex(text, -1))
+}
+
+// Chunk splits text into pieces of at most size tokens, each repeating the
+// last overlap tokens of the piece before it. Pieces are cut from text, so
+// they keep its spacing and line breaks.
+func Chunk(text string, size int, overlap int) []string {
+	tokens := tokenPattern.FindAllStringIndex(text, -1)
+	if len(tokens) == 0 {
+		return nil
+	}
+	chunks := make([]string, 0, len(tokens)/(size-overlap)+1)
+	for start := 0; ; start += size - overlap {
+		end := min(start+size, len(tokens))
+		chunks = append(chunks, text[tokens[start][0]:tokens[end-1][1]])
+		if end == len(tokens) {
+			return chunks
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document is a chunk of a plain text, Markdown or EPUB source, small enough
+// to embed, along with where it came from so that answers can cite it.
+type Document struct {
+	ID string `json:"id"`
+	// Source is the file the chunk was read from, relative to the parsed
+	// location.
+	Source string `json:"source"`
+	// Title is the title of the source and Sections the path of headings
+	// leading to the chunk within it, outermost first.
+	Title    string   `json:"title,omitempty"`
+	Sections []string `json:"sections,omitempty"`
+	// Chapter is the 1-based position of the chapter holding the chunk in the
+	// reading order of an EPUB, and zero for other sources.
+	Chapter int `json:"chapter,omitempty"`
+	// Chunk is the 1-based position of the chunk within its source.
+	Chunk     int       `json:"chunk"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Path joins the title and section headings of the document, such as
+// "Meditations > Book Two".
+func (d *Document) Path() string {
+	parts := make([]string, 0, len(d.Sections)+1)
+	if d.Title != "" {
+		parts = append(parts, d.Title)
+	}
+	for _, section := range d.Sections {
+		if len(parts) == 0 || parts[len(parts)-1] != section {
+			parts = append(parts, section)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// Citation names where the chunk comes from for answers to cite, adding the
+// chapter number of EPUB chunks to their path, such as
+// "Walden, chapter 2 > Where I Lived".
+func (d *Document) Citation() string {
+	if d.Chapter == 0 {
+		return d.Path()
+	}
+	chapter := fmt.Sprintf("chapter %d", d.Chapter)
+	if d.Title != "" {
+		chapter = d.Title + ", " + chapter
+	}
+	return (&Document{Title: chapter, Sections: d.Sections}).Path()
+}
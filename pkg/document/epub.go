@@ -0,0 +1,264 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"nvoke/pkg/diagnostic"
+	"path"
+	"strings"
+)
+
+// container is META-INF/container.xml, which locates the package document.
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// packageDocument is the OPF file listing the book's files and reading order.
+type packageDocument struct {
+	Titles   []string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// splitEPUB reads the chapters of an EPUB in spine order and splits them at
+// their headings. Chapters are numbered from 1 in reading order, skipping
+// spine items without text such as cover images. The title is the book's
+// title, falling back to title.
+func splitEPUB(source []byte, title string, report *diagnostic.Reporter) ([]section, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(source), int64(len(source)))
+	if err != nil {
+		return nil, title, report.Errorf(0, 0, "invalid EPUB: %v", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var root container
+	if err := decodeZipXML(files, "META-INF/container.xml", &root); err != nil {
+		return nil, title, report.Errorf(0, 0, "invalid EPUB: %v", err)
+	}
+	if len(root.Rootfiles) == 0 {
+		return nil, title, report.Errorf(0, 0, "invalid EPUB: no package document")
+	}
+	opfPath := root.Rootfiles[0].FullPath
+	var opf packageDocument
+	if err := decodeZipXML(files, opfPath, &opf); err != nil {
+		return nil, title, report.Errorf(0, 0, "invalid EPUB: %v", err)
+	}
+	if len(opf.Titles) > 0 && strings.TrimSpace(opf.Titles[0]) != "" {
+		title = strings.TrimSpace(opf.Titles[0])
+	}
+
+	items := make(map[string]string, len(opf.Manifest))
+	for _, item := range opf.Manifest {
+		items[item.ID] = ""
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			items[item.ID] = item.Href
+		}
+	}
+	sections := make([]section, 0)
+	chapter := 0
+	read := make(map[string]bool)
+	for _, itemref := range opf.Spine {
+		if itemref.Linear == "no" {
+			continue
+		}
+		href, ok := items[itemref.IDRef]
+		if !ok {
+			report.Warnf(0, 0, "spine item %q is not in the manifest", itemref.IDRef)
+		}
+		if href == "" {
+			continue
+		}
+		// A fragment names a place within the file, which is read whole,
+		// so only the first reference to a file is read.
+		href, _, _ = strings.Cut(href, "#")
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		name := path.Join(path.Dir(opfPath), href)
+		if read[name] {
+			continue
+		}
+		read[name] = true
+		file, ok := files[name]
+		if !ok {
+			report.Warnf(0, 0, "%s: missing from the EPUB", name)
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, title, fmt.Errorf("error reading %s: %v", name, err)
+		}
+		parsed := splitXHTML(content)
+		if !hasText(parsed) {
+			continue
+		}
+		chapter++
+		for i := range parsed {
+			parsed[i].chapter = chapter
+		}
+		sections = append(sections, parsed...)
+	}
+	return sections, title, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s not found", name)
+	}
+	content, err := readZipFile(file)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func hasText(sections []section) bool {
+	for _, s := range sections {
+		if strings.TrimSpace(s.text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// headingLevels maps the XHTML heading elements to their level.
+var headingLevels = map[string]int{"h1": 1, "h2": 2, "h3": 3, "h4": 4, "h5": 5, "h6": 6}
+
+// blockElements end a paragraph of the extracted text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "blockquote": true,
+	"li": true, "ul": true, "ol": true, "dd": true, "dt": true, "tr": true,
+	"table": true, "pre": true, "br": true, "hr": true,
+}
+
+// skippedElements hold no readable text.
+var skippedElements = map[string]bool{"head": true, "script": true, "style": true}
+
+// splitXHTML extracts the text of a chapter as paragraphs separated by blank
+// lines and splits it at its headings. The decoder is lenient so that the
+// HTML found in older EPUBs can be read as well as XHTML.
+func splitXHTML(content []byte) []section {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	sections := make([]section, 0)
+	headings := make([]string, 0)
+	var body, heading strings.Builder
+	level, skip := 0, 0
+	paragraph := func(b *strings.Builder) {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n\n") {
+			trimmed := strings.TrimRight(b.String(), " \n")
+			b.Reset()
+			b.WriteString(trimmed)
+			b.WriteString("\n\n")
+		}
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case skippedElements[name]:
+				skip++
+			case headingLevels[name] > 0 && level == 0:
+				paragraph(&body)
+				sections = append(sections, section{path: append([]string(nil), headings...), text: strings.TrimSpace(body.String())})
+				body.Reset()
+				level = headingLevels[name]
+				heading.Reset()
+			case blockElements[name]:
+				paragraph(&body)
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case skippedElements[name]:
+				if skip > 0 {
+					skip--
+				}
+			case headingLevels[name] == level && level > 0:
+				if text := collapseSpace(heading.String()); text != "" {
+					if level > len(headings)+1 {
+						level = len(headings) + 1
+					}
+					headings = append(headings[:level-1], text)
+				}
+				level = 0
+			case blockElements[name]:
+				paragraph(&body)
+			}
+		case xml.CharData:
+			if skip > 0 {
+				continue
+			}
+			text := string(t)
+			if level > 0 {
+				heading.WriteString(text)
+				continue
+			}
+			if strings.TrimSpace(text) == "" {
+				if body.Len() > 0 && !strings.HasSuffix(body.String(), " ") && !strings.HasSuffix(body.String(), "\n") {
+					body.WriteByte(' ')
+				}
+				continue
+			}
+			collapsed := collapseSpace(text)
+			if startsWithSpace(text) && body.Len() > 0 && !strings.HasSuffix(body.String(), " ") && !strings.HasSuffix(body.String(), "\n") {
+				body.WriteByte(' ')
+			}
+			body.WriteString(collapsed)
+			if endsWithSpace(text) {
+				body.WriteByte(' ')
+			}
+		}
+	}
+	sections = append(sections, section{path: headings, text: strings.TrimSpace(body.String())})
+	return sections
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func startsWithSpace(text string) bool {
+	return text != strings.TrimLeft(text, " \t\r\n")
+}
+
+func endsWithSpace(text string) bool {
+	return text != strings.TrimRight(text, " \t\r\n")
+}
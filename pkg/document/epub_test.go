@@ -0,0 +1,82 @@
+package document
+
+import (
+	"archive/zip"
+	"nvoke/pkg/diagnostic"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var epubFiles = map[string]string{
+	"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+	"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Walden</dc:title></metadata>
+  <manifest>
+    <item id="cover" href="cover.jpg" media-type="image/jpeg"/>
+    <item id="blank" href="text/blank.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch1" href="text/economy.xhtml#economy" media-type="application/xhtml+xml"/>
+    <item id="ch1-end" href="text/economy.xhtml#end" media-type="application/xhtml+xml"/>
+    <item id="ch2" href="text/where%20i%20lived.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine><itemref idref="cover"/><itemref idref="blank"/><itemref idref="ch2"/><itemref idref="ch1"/><itemref idref="ch1-end"/><itemref idref="notes"/></spine>
+</package>`,
+	"OEBPS/text/blank.xhtml": `<html><head><title>Blank</title></head><body></body></html>`,
+	"OEBPS/text/where i lived.xhtml": `<html><head><title>Where I Lived</title><style>p { margin: 0 }</style></head>
+<body>
+<h1>Where I Lived, and What I Lived For</h1>
+<p>I went to the <i>woods</i>
+   because I wished to live deliberately.</p>
+<h2>Morning</h2>
+<p>Every morning was a cheerful invitation&nbsp;to make my life<br>of equal simplicity.</p>
+</body></html>`,
+	"OEBPS/text/economy.xhtml": `<html><body><h1>Economy</h1><p>When I wrote the following pages.</p></body></html>`,
+}
+
+func TestParser_EPUB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "walden.epub")
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	archive := zip.NewWriter(file)
+	for name, content := range epubFiles {
+		writer, err := archive.Create(name)
+		assert.NoError(t, err)
+		_, err = writer.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, archive.Close())
+	assert.NoError(t, file.Close())
+
+	parser := &Parser{Mode: diagnostic.Strict}
+	documents, err := parser.Parse(path)
+	assert.NoError(t, err)
+	assert.Len(t, parser.Diagnostics(), 1, "notes are not in the manifest")
+	if assert.Len(t, documents, 3) {
+		assert.Equal(t, "walden.epub#where-i-lived-and-what-i-lived-for.1", documents[0].ID)
+		assert.Equal(t, "Walden, chapter 1 > Where I Lived, and What I Lived For", documents[0].Citation())
+		assert.Equal(t, "I went to the woods because I wished to live deliberately.", documents[0].Text)
+		assert.Equal(t, []string{"Where I Lived, and What I Lived For", "Morning"}, documents[1].Sections)
+		assert.Equal(t, "Every morning was a cheerful invitation to make my life\n\nof equal simplicity.", documents[1].Text)
+		assert.Equal(t, 2, documents[2].Chapter)
+		assert.Equal(t, 3, documents[2].Chunk)
+		assert.Equal(t, "Walden > Economy", documents[2].Path())
+	}
+}
+
+func TestSplitEPUB_Invalid(t *testing.T) {
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	sections, title, err := splitEPUB([]byte("not a zip"), "walden", report)
+	assert.NoError(t, err)
+	assert.Empty(t, sections)
+	assert.Equal(t, "walden", title)
+	assert.Len(t, report.Diagnostics.Errors(), 1)
+
+	_, _, err = splitEPUB([]byte("not a zip"), "walden", diagnostic.NewReporter(diagnostic.Strict))
+	assert.Error(t, err)
+}
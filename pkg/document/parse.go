@@ -0,0 +1,334 @@
+package document
+
+import (
+	"fmt"
+	"io/fs"
+	"nvoke/pkg/diagnostic"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Source file syntaxes understood by Parser.
+const (
+	SyntaxMarkdown = "markdown"
+	SyntaxText     = "text"
+	SyntaxEPUB     = "epub"
+)
+
+// extensions maps the file extensions read from a directory to their syntax.
+var extensions = map[string]string{
+	".md":       SyntaxMarkdown,
+	".markdown": SyntaxMarkdown,
+	".txt":      SyntaxText,
+	".epub":     SyntaxEPUB,
+}
+
+type Parser struct {
+	Mode diagnostic.Mode
+	// Syntax is the format of every source file. When empty it is chosen by
+	// file extension, and files with other extensions are read as text.
+	Syntax string
+	// SectionPattern matches the lines that start a new section of a plain
+	// text file, such as `^BOOK ([IVX]+)$`. The first group names the
+	// section, or the whole line when the pattern has no groups.
+	SectionPattern string
+	// ChunkTokens and ChunkOverlap size the chunks. ChunkTokens defaults to
+	// DefaultChunkTokens, and ChunkOverlap to the same share of the chunk
+	// size as DefaultChunkOverlap is of DefaultChunkTokens.
+	ChunkTokens  int
+	ChunkOverlap int
+	diagnostics  diagnostic.List
+}
+
+// section is a run of text under one heading path. chapter numbers the
+// chapters of an EPUB and is zero for other sources.
+type section struct {
+	path    []string
+	text    string
+	chapter int
+}
+
+// Parse reads the plain text, Markdown and EPUB files at location, a single file
+// or a directory searched recursively, and splits them into chunks.
+func (p *Parser) Parse(location string) ([]*Document, error) {
+	report := diagnostic.NewReporter(p.Mode)
+	defer func() { p.diagnostics = report.Diagnostics }()
+
+	size, overlap := p.ChunkTokens, p.ChunkOverlap
+	if size == 0 {
+		size = DefaultChunkTokens
+	}
+	if overlap == 0 {
+		overlap = size * DefaultChunkOverlap / DefaultChunkTokens
+	}
+	if size <= 0 || overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("chunk overlap %d must be less than chunk size %d", overlap, size)
+	}
+	var pattern *regexp.Regexp
+	if p.SectionPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(p.SectionPattern); err != nil {
+			return nil, fmt.Errorf("invalid section pattern: %v", err)
+		}
+	}
+
+	files, err := sourceFiles(location)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]*Document, 0)
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", file, err)
+		}
+		report.File = file
+		syntax := p.Syntax
+		if syntax == "" {
+			syntax = extensions[strings.ToLower(filepath.Ext(file))]
+		}
+		title := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		var sections []section
+		switch syntax {
+		case SyntaxEPUB:
+			if sections, title, err = splitEPUB(source, title, report); err != nil {
+				return nil, err
+			}
+		case SyntaxMarkdown:
+			sections, title = splitMarkdown(string(source), title, report)
+		case SyntaxText, "":
+			sections = splitText(string(source), pattern)
+		default:
+			return nil, report.Errorf(0, 0, "unrecognized source syntax %q", syntax)
+		}
+		name, err := filepath.Rel(location, file)
+		if err != nil || name == "." {
+			name = filepath.Base(file)
+		}
+		parsed := chunkSections(name, title, sections, size, overlap)
+		if len(parsed) == 0 {
+			report.Warnf(0, 0, "no text")
+		}
+		documents = append(documents, parsed...)
+	}
+	return documents, nil
+}
+
+// ParserVersion is the version of the chunks Parser produces.
+const ParserVersion = 2
+
+func (p *Parser) Version() int {
+	return ParserVersion
+}
+
+func (p *Parser) Diagnostics() diagnostic.List {
+	return p.diagnostics
+}
+
+// sourceFiles returns location when it is a file, or the files with a known
+// extension below it in path order, skipping hidden files and directories.
+func sourceFiles(location string) ([]string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{location}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.WalkDir(location, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != location {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok && !entry.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// chunkSections turns the sections of a source into documents numbered in
+// source order. IDs name the section and the position of the chunk within
+// it, so that editing one section leaves the IDs of the others unchanged.
+func chunkSections(source string, title string, sections []section, size int, overlap int) []*Document {
+	documents := make([]*Document, 0)
+	occurrences := make(map[string]int)
+	for _, s := range sections {
+		key := sectionKey(s)
+		if occurrences[key]++; occurrences[key] > 1 {
+			key = fmt.Sprintf("%s~%d", key, occurrences[key])
+		}
+		for i, text := range Chunk(s.text, size, overlap) {
+			id := fmt.Sprintf("%s#%d", filepath.ToSlash(source), i+1)
+			if key != "" {
+				id = fmt.Sprintf("%s#%s.%d", filepath.ToSlash(source), key, i+1)
+			}
+			documents = append(documents, &Document{
+				ID:       id,
+				Source:   filepath.ToSlash(source),
+				Title:    title,
+				Sections: s.path,
+				Chapter:  s.chapter,
+				Chunk:    len(documents) + 1,
+				Text:     text,
+			})
+		}
+	}
+	return documents
+}
+
+// sectionKey identifies a section within its source by its heading path,
+// such as "birds/herons".
+func sectionKey(s section) string {
+	parts := make([]string, len(s.path))
+	for i, heading := range s.path {
+		parts[i] = slug(heading)
+	}
+	return strings.Join(parts, "/")
+}
+
+// slug lowercases heading and joins its words with hyphens.
+func slug(heading string) string {
+	words := strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "section"
+	}
+	return strings.Join(words, "-")
+}
+
+// splitText splits plain text into the sections started by lines matching
+// pattern. Text before the first match, or all of it when pattern is nil,
+// has no section.
+func splitText(source string, pattern *regexp.Regexp) []section {
+	sections := make([]section, 0)
+	current := section{}
+	var body strings.Builder
+	for _, line := range strings.Split(source, "\n") {
+		if pattern != nil {
+			if match := pattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+				current.text = body.String()
+				sections = append(sections, current)
+				name := match[0]
+				if len(match) > 1 {
+					name = match[1]
+				}
+				current = section{path: []string{strings.TrimSpace(name)}}
+				body.Reset()
+				continue
+			}
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	current.text = body.String()
+	return append(sections, current)
+}
+
+var (
+	atxHeading     = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	setextHeading  = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	codeFence      = regexp.MustCompile("^ {0,3}(```|~~~)")
+	frontMatterKey = regexp.MustCompile(`^title:\s*["']?(.*?)["']?\s*$`)
+)
+
+// splitMarkdown splits a Markdown document at its headings, giving each
+// section the path of headings above it. The title is taken from YAML front
+// matter or the first level 1 heading, falling back to title.
+func splitMarkdown(source string, title string, report *diagnostic.Reporter) ([]section, string) {
+	lines := strings.Split(source, "\n")
+	start := 0
+	titled := false
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				start = i + 1
+				break
+			}
+			if match := frontMatterKey.FindStringSubmatch(strings.TrimSpace(lines[i])); match != nil {
+				title = match[1]
+				titled = true
+			}
+		}
+	}
+
+	sections := make([]section, 0)
+	headings := make([]string, 0)
+	body := make([]string, 0)
+	flush := func() {
+		sections = append(sections, section{
+			path: append([]string(nil), headings...),
+			text: strings.Join(body, "\n"),
+		})
+		body = body[:0]
+	}
+	heading := func(level int, text string) {
+		flush()
+		if level == 1 && !titled {
+			title = text
+			titled = true
+		}
+		if level > len(headings)+1 {
+			level = len(headings) + 1
+		}
+		headings = append(headings[:level-1], text)
+	}
+
+	fence, fenceLine := "", 0
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if match := codeFence.FindStringSubmatch(line); match != nil {
+			switch {
+			case fence == "":
+				fence, fenceLine = match[1], i+1
+			case fence == match[1]:
+				fence = ""
+			}
+			body = append(body, line)
+			continue
+		}
+		if fence != "" {
+			body = append(body, line)
+			continue
+		}
+		if match := atxHeading.FindStringSubmatch(line); match != nil {
+			heading(len(match[1]), match[2])
+			continue
+		}
+		// A single line of text underlined with = or - is a heading.
+		if match := setextHeading.FindStringSubmatch(line); match != nil && len(body) > 0 {
+			previous := strings.TrimSpace(body[len(body)-1])
+			if previous != "" && (len(body) == 1 || strings.TrimSpace(body[len(body)-2]) == "") {
+				body = body[:len(body)-1]
+				level := 1
+				if match[1][0] == '-' {
+					level = 2
+				}
+				heading(level, previous)
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	if fence != "" {
+		report.Warnf(fenceLine, 1, "code block is not closed with %s", fence)
+	}
+	flush()
+	return sections, title
+}
@@ -0,0 +1,128 @@
+package document
+
+import (
+	"fmt"
+	"nvoke/pkg/diagnostic"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const markdown = `---
+title: "Field Notes"
+---
+Opening remarks.
+
+# Birds
+
+## Herons
+Herons wade in the shallows.
+
+` + "```" + `
+# not a heading
+` + "```" + `
+
+Owls
+----
+Owls hunt at night.
+`
+
+func TestParser_Markdown(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.md"), []byte(markdown), 0o644))
+
+	parser := &Parser{Mode: diagnostic.Strict}
+	documents, err := parser.Parse(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, parser.Diagnostics())
+	if assert.Len(t, documents, 3) {
+		assert.Equal(t, "notes.md#1", documents[0].ID)
+		assert.Equal(t, "notes.md#birds/herons.1", documents[1].ID)
+		assert.Equal(t, "notes.md#birds/owls.1", documents[2].ID)
+		assert.Equal(t, "Field Notes", documents[0].Path())
+		assert.Equal(t, "Opening remarks.", documents[0].Text)
+		assert.Equal(t, []string{"Birds", "Herons"}, documents[1].Sections)
+		assert.Contains(t, documents[1].Text, "# not a heading")
+		assert.Equal(t, "Field Notes > Birds > Owls", documents[2].Path())
+		assert.Equal(t, 3, documents[2].Chunk)
+	}
+}
+
+func TestParser_SectionPattern(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meditations.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("BOOK I\none two three four five\nBOOK II\nsix seven\n"), 0o644))
+
+	parser := &Parser{SectionPattern: `^BOOK ([IVX]+)$`, ChunkTokens: 3, ChunkOverlap: 1}
+	documents, err := parser.Parse(path)
+	assert.NoError(t, err)
+	texts := make([]string, 0)
+	for _, document := range documents {
+		texts = append(texts, document.Path()+": "+document.Text)
+	}
+	assert.Equal(t, []string{
+		"meditations > I: one two three",
+		"meditations > I: three four five",
+		"meditations > II: six seven",
+	}, texts)
+
+	_, err = (&Parser{ChunkTokens: 10, ChunkOverlap: 10}).Parse(path)
+	assert.Error(t, err)
+}
+
+func TestParser_StableIDs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	ids := func(source string) []string {
+		assert.NoError(t, os.WriteFile(path, []byte(source), 0o644))
+		documents, err := (&Parser{ChunkTokens: 4, ChunkOverlap: 1}).Parse(path)
+		assert.NoError(t, err)
+		ids := make([]string, 0)
+		for _, document := range documents {
+			ids = append(ids, document.ID)
+		}
+		return ids
+	}
+
+	before := ids("# Herons\none two three four\n# Owls\nfive six\n# Notes\nseven\n# Notes\neight\n")
+	assert.Equal(t, []string{"notes.md#herons.1", "notes.md#owls.1", "notes.md#notes.1", "notes.md#notes~2.1"}, before)
+
+	// Growing the first section adds chunks to it without renaming the
+	// chunks of the sections after it.
+	after := ids("# Herons\none two three four\n\nnine ten eleven\n# Owls\nfive six\n# Notes\nseven\n# Notes\neight\n")
+	assert.Equal(t, []string{"notes.md#herons.1", "notes.md#herons.2", "notes.md#owls.1", "notes.md#notes.1", "notes.md#notes~2.1"}, after)
+}
+
+func TestParser_ChunkOverlap(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "numbers.txt")
+	words := make([]string, 24)
+	for i := range words {
+		words[i] = fmt.Sprint(i + 1)
+	}
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(words, " ")), 0o644))
+
+	texts := func(parser *Parser) []string {
+		documents, err := parser.Parse(path)
+		assert.NoError(t, err)
+		texts := make([]string, 0)
+		for _, document := range documents {
+			texts = append(texts, document.Text)
+		}
+		return texts
+	}
+	// A chunk size without an overlap keeps the default share of overlap.
+	assert.Equal(t, []string{
+		"1 2 3 4 5 6 7 8 9 10 11 12",
+		"11 12 13 14 15 16 17 18 19 20 21 22",
+		"21 22 23 24",
+	}, texts(&Parser{ChunkTokens: 12}))
+	assert.Equal(t, []string{
+		"1 2 3 4 5 6 7 8 9 10 11 12",
+		"10 11 12 13 14 15 16 17 18 19 20 21",
+		"19 20 21 22 23 24",
+	}, texts(&Parser{ChunkTokens: 12, ChunkOverlap: 3}))
+}
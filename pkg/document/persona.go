@@ -0,0 +1,40 @@
+package document
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultPrompt is the system prompt of a corpus configured without one.
+const DefaultPrompt = "You are a helpful assistant. You answer questions about the texts you are given, citing the section each answer comes from."
+
+// Persona answers from the chunks of a configured corpus in the voice given
+// by its prompt.
+type Persona struct {
+	prompt string
+}
+
+// NewPersona returns a persona using prompt, or DefaultPrompt when it is
+// empty.
+func NewPersona(prompt string) *Persona {
+	if prompt == "" {
+		prompt = DefaultPrompt
+	}
+	return &Persona{prompt: prompt}
+}
+
+func (p *Persona) BuildCompletionContext(ctx context.Context, items []interface{}) (string, error) {
+	contextString := "Using the following passages for context to answer the question. Do not use other information or sources. \n context: "
+	for _, val := range items {
+		document, ok := val.(*Document)
+		if !ok {
+			return "", fmt.Errorf("unexpected document type %T", val)
+		}
+		contextString += fmt.Sprintf("[%v] -- %v ", document.Citation(), document.Text)
+	}
+	return contextString, nil
+}
+
+func (p *Persona) Prompt() string {
+	return p.prompt
+}
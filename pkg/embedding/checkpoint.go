@@ -0,0 +1,135 @@
+package embedding
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// Checkpoint persists completed embeddings so an interrupted run can resume.
+type Checkpoint interface {
+	Load() (map[string][]float32, error)
+	Save(id string, embedding []float32) error
+	Close() error
+}
+
+type checkpointRecord struct {
+	ID        string    `json:"id"`
+	Embedding []float32 `json:"embedding"`
+	// Provenance is only set on the first record, which holds no embedding.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// checkpointHeader is the first record of a checkpoint, naming the model
+// that produced its embeddings.
+type checkpointHeader struct {
+	Provenance Provenance `json:"provenance"`
+}
+
+// FileCheckpoint is a Checkpoint that appends one JSON record per line to a file.
+type FileCheckpoint struct {
+	path     string
+	file     *os.File
+	mu       sync.Mutex
+	recorded *Provenance
+}
+
+// NewFileCheckpoint opens the checkpoint at path for embeddings produced
+// with provenance. Unless resume is set any existing checkpoint is discarded.
+func NewFileCheckpoint(path string, resume bool, provenance Provenance) (*FileCheckpoint, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err != nil || info.Size() > 0 {
+		return &FileCheckpoint{path: path, file: file}, err
+	}
+	bytes, err := json.Marshal(checkpointHeader{Provenance: provenance})
+	if err == nil {
+		_, err = file.Write(append(bytes, '\n'))
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileCheckpoint{path: path, file: file}, nil
+}
+
+// Provenance returns the provenance recorded in the checkpoint as of the last
+// Load, or nil for a checkpoint written before provenance was recorded.
+func (c *FileCheckpoint) Provenance() *Provenance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recorded
+}
+
+// Load reads every embedding recorded so far keyed by document ID. A partially
+// written final line, as left behind by a crash, is ignored.
+func (c *FileCheckpoint) Load() (map[string][]float32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	done := make(map[string][]float32)
+	reader := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		bytes, err := reader.ReadBytes('\n')
+		if len(bytes) > 0 {
+			var record checkpointRecord
+			if jsonErr := json.Unmarshal(bytes, &record); jsonErr != nil {
+				if err == nil {
+					return nil, fmt.Errorf("%s:%d: %v", c.path, line, jsonErr)
+				}
+				log.Printf("Ignoring incomplete checkpoint record at %s:%d\n", c.path, line)
+			} else if record.Provenance != nil {
+				c.recorded = record.Provenance
+			} else {
+				done[record.ID] = record.Embedding
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return done, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Save appends a completed embedding to the checkpoint.
+func (c *FileCheckpoint) Save(id string, embedding []float32) error {
+	bytes, err := json.Marshal(checkpointRecord{ID: id, Embedding: embedding})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.file.Write(append(bytes, '\n'))
+	return err
+}
+
+func (c *FileCheckpoint) Close() error {
+	return c.file.Close()
+}
+
+// Remove closes and deletes the checkpoint once it is no longer needed.
+func (c *FileCheckpoint) Remove() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+	return os.Remove(c.path)
+}
@@ -2,8 +2,11 @@ package embedding
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,11 +29,57 @@ type Adapter[T any] interface {
 	EmbeddingStore[T]
 }
 
+// Identifier is implemented by adapters that can give each item a stable ID.
+// It is required when generating embeddings with a Checkpoint.
+type Identifier[T any] interface {
+	GetID(item T) string
+}
+
+// EmbeddingReader is implemented by adapters that can return an item's
+// stored embedding.
+type EmbeddingReader[T any] interface {
+	GetEmbedding(item T) []float32
+}
+
+// ErrIdentifierRequired is returned when checkpointing with an adapter that
+// does not implement Identifier.
+var ErrIdentifierRequired = errors.New("checkpointing requires an adapter that implements Identifier")
+
 // Generator defines the interface for generating embeddings.
 type Generator interface {
 	GenerateEmbedding(context context.Context, content string) ([]float32, error)
 }
 
+// Provenance records which model and dimensions produced a set of embeddings.
+type Provenance struct {
+	Model       string    `json:"model" bson:"model"`
+	Dimensions  int       `json:"dimensions" bson:"dimensions"`
+	GeneratedAt time.Time `json:"generated_at" bson:"generated_at"`
+}
+
+// Matches reports whether embeddings from both sources are comparable.
+func (p Provenance) Matches(other Provenance) bool {
+	return p.Model == other.Model && p.Dimensions == other.Dimensions
+}
+
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s/%d", p.Model, p.Dimensions)
+}
+
+// Describer is implemented by generators that can report their provenance.
+type Describer interface {
+	Provenance() Provenance
+}
+
+// Describe returns the provenance of generator if it implements Describer.
+func Describe(generator Generator) (Provenance, bool) {
+	describer, ok := generator.(Describer)
+	if !ok {
+		return Provenance{}, false
+	}
+	return describer.Provenance(), true
+}
+
 // RateLimiter defines the interface for adjusting worker settings based on performance.
 type RateLimiter interface {
 	AdjustConcurrency(elapsed time.Duration) int
@@ -44,6 +93,42 @@ type Service[T any] struct {
 	Generator Generator
 	Adapter   Adapter[T]
 	Limiter   RateLimiter
+	// Backoff builds the retry policy used for each item.
+	Backoff func() backoff.BackOff
+	// Checkpoint optionally records each completed item so a later run can
+	// skip it.
+	Checkpoint Checkpoint
+	// Observer is notified as items and batches complete.
+	Observer Observer
+	// Sink, when set, receives each item once its embedding is stored,
+	// including items restored from the Checkpoint, so that results can be
+	// written out as they complete. It may be called from several goroutines
+	// at once, and an error from it stops the run after the current batch.
+	Sink func(item T) error
+}
+
+// Failure records an item whose embedding could not be generated.
+type Failure[T any] struct {
+	Index int
+	Item  T
+	Err   error
+}
+
+// Result summarises a call to GenerateEmbeddings.
+type Result[T any] struct {
+	Succeeded int
+	Resumed   int
+	Failed    []Failure[T]
+	Elapsed   time.Duration
+}
+
+// FailedItems returns the items that could not be embedded.
+func (r *Result[T]) FailedItems() []T {
+	items := make([]T, len(r.Failed))
+	for i, failure := range r.Failed {
+		items[i] = failure.Item
+	}
+	return items
 }
 
 // NewService creates a new instance of EmbeddingService.
@@ -52,17 +137,65 @@ func NewService[T any](generator Generator, adapter Adapter[T], limiter RateLimi
 		Generator: generator,
 		Adapter:   adapter,
 		Limiter:   limiter,
+		Backoff:   DefaultBackoff,
+		Observer:  LogObserver{},
 	}
 }
 
-// Generate processes all verses and handles worker adjustments.
-func (es *Service[T]) GenerateEmbeddings(ctx context.Context, items []T) error {
-	var total int64
+// DefaultBackoff retries with exponential backoff for up to a minute.
+func DefaultBackoff() backoff.BackOff {
+	return backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(1 * time.Minute))
+}
+
+// GenerateEmbeddings processes all items and handles worker adjustments. Items
+// that still fail after retrying are reported in the result rather than
+// aborting the run. An error is returned if the context is cancelled or the
+// checkpoint cannot be read.
+func (es *Service[T]) GenerateEmbeddings(ctx context.Context, items []T) (*Result[T], error) {
+	var total, completed, inFlight int64
+	var mu sync.Mutex
+	result := &Result[T]{}
+	begin := time.Now()
+	observer := es.observer()
+
+	pending, err := es.restore(items, result)
+	if err != nil {
+		return nil, err
+	}
+	completed = int64(result.Resumed)
+	var sinkErr error
+	sink := func(item T) {
+		if es.Sink == nil {
+			return
+		}
+		if err := es.Sink(item); err != nil {
+			mu.Lock()
+			if sinkErr == nil {
+				sinkErr = err
+			}
+			mu.Unlock()
+		}
+	}
+	if result.Resumed > 0 {
+		queued := make([]bool, len(items))
+		for _, index := range pending {
+			queued[index] = true
+		}
+		for i, item := range items {
+			if !queued[i] {
+				sink(item)
+			}
+		}
+		if sinkErr != nil {
+			return result, sinkErr
+		}
+	}
+
 	wg := &sync.WaitGroup{}
-	for i := 0; i < len(items); i += es.Limiter.RequestLimit() {
-		var count int64
+	for i := 0; i < len(pending); i += es.Limiter.RequestLimit() {
+		var count, failed int64
 
-		totalItems := len(items)
+		totalItems := len(pending)
 		numWorkers := es.Limiter.Concurrency()
 		batchSize := min(es.Limiter.RequestLimit(), totalItems-i)
 		chunkSize := batchSize / numWorkers
@@ -84,38 +217,141 @@ func (es *Service[T]) GenerateEmbeddings(ctx context.Context, items []T) error {
 
 			go func(start, end int) {
 				defer wg.Done()
-				for _, item := range items[start:end] {
-					content := es.Adapter.GetContent(item)
-					var embedding []float32
-					operation := func() error {
-						var err error
-						embedding, err = es.Generator.GenerateEmbedding(ctx, content)
-						if err != nil {
-							log.Printf("Retrying embedding generation: %v '%v'", err, content)
-							return err
-						}
-						return nil
+				for _, index := range pending[start:end] {
+					item := items[index]
+					event := ItemEvent{
+						Index:     index,
+						InFlight:  int(atomic.AddInt64(&inFlight, 1)),
+						Completed: int(atomic.LoadInt64(&completed)),
+						Total:     len(items),
 					}
+					observer.ItemStarted(event)
 
-					expBackoff := backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(1 * time.Minute))
-					err := backoff.Retry(operation, expBackoff)
+					itemStart := time.Now()
+					embedding, err := es.generate(ctx, es.Adapter.GetContent(item))
+					event.Latency = time.Since(itemStart)
+					event.InFlight = int(atomic.AddInt64(&inFlight, -1))
+					event.Completed = int(atomic.AddInt64(&completed, 1))
 					if err != nil {
 						log.Printf("Error generating embeddings: %v '%v'", err, item)
+						mu.Lock()
+						result.Failed = append(result.Failed, Failure[T]{Index: index, Item: item, Err: err})
+						mu.Unlock()
+						atomic.AddInt64(&failed, 1)
+						event.Err = err
+						observer.ItemFailed(event)
 						continue
 					}
 
 					es.Adapter.StoreEmbedding(item, embedding)
+					es.save(item, embedding)
+					sink(item)
 					atomic.AddInt64(&count, 1)
+					observer.ItemSucceeded(event)
 				}
 			}(start, end)
 		}
 		wg.Wait()
 		total += count
 		elapsed := time.Since(startTime)
-		log.Printf("Processed %d verses in %s with %d workers. Total processed %d\n", count, elapsed, numWorkers, total)
+		observer.BatchCompleted(BatchEvent{
+			Succeeded: int(count),
+			Failed:    int(failed),
+			Workers:   numWorkers,
+			Elapsed:   elapsed,
+			Completed: int(atomic.LoadInt64(&completed)),
+			Total:     len(items),
+		})
+		if err := ctx.Err(); err != nil {
+			result.Succeeded = int(total)
+			result.Elapsed = time.Since(begin)
+			return result, err
+		}
+		if sinkErr != nil {
+			result.Succeeded = int(total)
+			result.Elapsed = time.Since(begin)
+			return result, sinkErr
+		}
 		es.Limiter.AdjustConcurrency(elapsed)
 	}
-	return nil
+	sort.Slice(result.Failed, func(a, b int) bool {
+		return result.Failed[a].Index < result.Failed[b].Index
+	})
+	result.Succeeded = int(total)
+	result.Elapsed = time.Since(begin)
+	return result, nil
+}
+
+func (es *Service[T]) observer() Observer {
+	if es.Observer == nil {
+		return Observers{}
+	}
+	return es.Observer
+}
+
+// restore applies embeddings already recorded in the checkpoint and returns
+// the indices of the items that still need to be generated.
+func (es *Service[T]) restore(items []T, result *Result[T]) ([]int, error) {
+	pending := make([]int, 0, len(items))
+	if es.Checkpoint == nil {
+		for i := range items {
+			pending = append(pending, i)
+		}
+		return pending, nil
+	}
+
+	identifier, ok := es.Adapter.(Identifier[T])
+	if !ok {
+		return nil, ErrIdentifierRequired
+	}
+	done, err := es.Checkpoint.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+	for i, item := range items {
+		embedding, ok := done[identifier.GetID(item)]
+		if !ok {
+			pending = append(pending, i)
+			continue
+		}
+		es.Adapter.StoreEmbedding(item, embedding)
+		result.Resumed++
+	}
+	if result.Resumed > 0 {
+		log.Printf("Resumed %d items from checkpoint, %d remaining\n", result.Resumed, len(pending))
+	}
+	return pending, nil
+}
+
+// save records a completed item in the checkpoint, if there is one.
+func (es *Service[T]) save(item T, embedding []float32) {
+	if es.Checkpoint == nil {
+		return
+	}
+	id := es.Adapter.(Identifier[T]).GetID(item)
+	if err := es.Checkpoint.Save(id, embedding); err != nil {
+		log.Printf("Failed to checkpoint %v: %v", id, err)
+	}
+}
+
+// generate retries the generator until it succeeds or the backoff policy gives up.
+func (es *Service[T]) generate(ctx context.Context, content string) ([]float32, error) {
+	var embedding []float32
+	operation := func() error {
+		var err error
+		embedding, err = es.Generator.GenerateEmbedding(ctx, content)
+		if err != nil {
+			log.Printf("Retrying embedding generation: %v '%v'", err, content)
+			return err
+		}
+		return nil
+	}
+	policy := es.Backoff
+	if policy == nil {
+		policy = DefaultBackoff
+	}
+	err := backoff.Retry(operation, backoff.WithContext(policy(), ctx))
+	return embedding, err
 }
 
 type Client interface {
@@ -150,6 +386,14 @@ func (sg *OpenAIGenerator) GenerateEmbedding(context context.Context, content st
 	return response.Data[0].Embedding, nil
 }
 
+func (sg *OpenAIGenerator) Provenance() Provenance {
+	return Provenance{
+		Model:       string(sg.Model),
+		Dimensions:  sg.Dimensions,
+		GeneratedAt: time.Now().UTC(),
+	}
+}
+
 // SteadyRateLimiter is an implementation of the WorkerAdjuster interface.
 type SteadyRateLimiter struct {
 	concurrency  int
@@ -187,6 +431,25 @@ func (sa *SteadyRateLimiter) Period() time.Duration {
 	return sa.period
 }
 
+// CappedRateLimiter keeps the concurrency of a RateLimiter at or below Max.
+type CappedRateLimiter struct {
+	RateLimiter
+	Max int
+}
+
+// CapConcurrency limits limiter to at most max concurrent workers.
+func CapConcurrency(limiter RateLimiter, max int) *CappedRateLimiter {
+	return &CappedRateLimiter{RateLimiter: limiter, Max: max}
+}
+
+func (cl *CappedRateLimiter) AdjustConcurrency(elapsed time.Duration) int {
+	return min(cl.RateLimiter.AdjustConcurrency(elapsed), cl.Max)
+}
+
+func (cl *CappedRateLimiter) Concurrency() int {
+	return min(cl.RateLimiter.Concurrency(), cl.Max)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -200,3 +463,32 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+// TruncatingGenerator derives shorter embeddings from a full size Generator.
+type TruncatingGenerator struct {
+	Generator  Generator
+	Dimensions int
+}
+
+func NewTruncatingGenerator(generator Generator, dimensions int) *TruncatingGenerator {
+	return &TruncatingGenerator{
+		Generator:  generator,
+		Dimensions: dimensions,
+	}
+}
+
+func (tg *TruncatingGenerator) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	embedding, err := tg.Generator.GenerateEmbedding(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	return Truncate(embedding, tg.Dimensions), nil
+}
+
+func (tg *TruncatingGenerator) Provenance() Provenance {
+	provenance, _ := Describe(tg.Generator)
+	if tg.Dimensions > 0 && tg.Dimensions < provenance.Dimensions {
+		provenance.Dimensions = tg.Dimensions
+	}
+	return provenance
+}
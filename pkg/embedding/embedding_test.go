@@ -2,9 +2,13 @@ package embedding
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -34,6 +38,15 @@ func (m *MockAdapter[T]) StoreEmbedding(item T, embedding []float32) {
 	m.Called(item, embedding)
 }
 
+// identifiedAdapter adds stable IDs to MockAdapter for checkpointing
+type identifiedAdapter struct {
+	MockAdapter[int]
+}
+
+func (a *identifiedAdapter) GetID(item int) string {
+	return fmt.Sprintf("item-%d", item)
+}
+
 type MockEmbeddingClient struct {
 	mock.Mock
 }
@@ -101,12 +114,126 @@ func TestEmbeddingService_GenerateEmbeddings(t *testing.T) {
 	mockAdapter.On("StoreEmbedding", mock.Anything, mock.Anything).Return()
 
 	items := []int{1, 2, 3}
-	err := service.GenerateEmbeddings(context.TODO(), items)
+	result, err := service.GenerateEmbeddings(context.TODO(), items)
 	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Empty(t, result.Failed)
 
 	mockGen.AssertExpectations(t)
 	mockAdapter.AssertExpectations(t)
 }
+
+// Testing EmbeddingService.GenerateEmbeddings when the generator gives up on some items
+func TestEmbeddingService_GenerateEmbeddingsFailures(t *testing.T) {
+	mockGen := &MockGenerator{}
+	mockAdapter := &MockAdapter[int]{}
+	mockLimiter := &SteadyRateLimiter{
+		concurrency:  2,
+		requestLimit: 4,
+		period:       time.Minute,
+	}
+
+	service := NewService[int](mockGen, mockAdapter, mockLimiter)
+	service.Backoff = func() backoff.BackOff { return &backoff.StopBackOff{} }
+
+	mockAdapter.On("GetContent", 2).Return("bad content")
+	mockAdapter.On("GetContent", mock.Anything).Return("good content")
+	mockAdapter.On("StoreEmbedding", mock.Anything, mock.Anything).Return()
+	mockGen.On("GenerateEmbedding", mock.Anything, "bad content").Return([]float32(nil), errors.New("rate limited"))
+	mockGen.On("GenerateEmbedding", mock.Anything, "good content").Return([]float32{0.1, 0.2, 0.3}, nil)
+
+	items := []int{1, 2, 3, 4}
+	result, err := service.GenerateEmbeddings(context.TODO(), items)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Len(t, result.Failed, 1)
+	assert.Equal(t, 1, result.Failed[0].Index)
+	assert.Equal(t, []int{2}, result.FailedItems())
+	assert.EqualError(t, result.Failed[0].Err, "rate limited")
+	mockAdapter.AssertNotCalled(t, "StoreEmbedding", 2, mock.Anything)
+}
+
+// Testing that EmbeddingService notifies its Observer
+func TestEmbeddingService_Observer(t *testing.T) {
+	mockGen := &MockGenerator{}
+	mockAdapter := &MockAdapter[int]{}
+	mockLimiter := &SteadyRateLimiter{
+		concurrency:  2,
+		requestLimit: 2,
+		period:       time.Minute,
+	}
+
+	metrics := NewMetrics()
+	service := NewService[int](mockGen, mockAdapter, mockLimiter)
+	service.Backoff = func() backoff.BackOff { return &backoff.StopBackOff{} }
+	service.Observer = metrics
+
+	mockAdapter.On("GetContent", 3).Return("bad content")
+	mockAdapter.On("GetContent", mock.Anything).Return("good content")
+	mockAdapter.On("StoreEmbedding", mock.Anything, mock.Anything).Return()
+	mockGen.On("GenerateEmbedding", mock.Anything, "bad content").Return([]float32(nil), errors.New("rate limited"))
+	mockGen.On("GenerateEmbedding", mock.Anything, "good content").Return([]float32{0.1}, nil)
+
+	_, err := service.GenerateEmbeddings(context.TODO(), []int{1, 2, 3, 4})
+	assert.NoError(t, err)
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, int64(4), snapshot.Started)
+	assert.Equal(t, int64(3), snapshot.Succeeded)
+	assert.Equal(t, int64(1), snapshot.Failed)
+	assert.Equal(t, int64(0), snapshot.InFlight)
+	assert.Equal(t, int64(2), snapshot.Batches)
+}
+
+// Testing EmbeddingService.GenerateEmbeddings resuming from a checkpoint
+func TestEmbeddingService_GenerateEmbeddingsResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	provenance := Provenance{Model: "text-embedding-3-small", Dimensions: 1}
+	checkpoint, err := NewFileCheckpoint(path, false, provenance)
+	assert.NoError(t, err)
+	assert.NoError(t, checkpoint.Save("item-1", []float32{1}))
+	assert.NoError(t, checkpoint.Save("item-2", []float32{2}))
+	assert.NoError(t, checkpoint.Close())
+
+	checkpoint, err = NewFileCheckpoint(path, true, Provenance{Model: "text-embedding-3-large", Dimensions: 1})
+	assert.NoError(t, err)
+	defer checkpoint.Close()
+
+	mockGen := &MockGenerator{}
+	mockAdapter := &identifiedAdapter{}
+	mockLimiter := &SteadyRateLimiter{
+		concurrency:  1,
+		requestLimit: 10,
+		period:       time.Minute,
+	}
+	service := NewService[int](mockGen, mockAdapter, mockLimiter)
+	service.Checkpoint = checkpoint
+	written := make([]int, 0)
+	service.Sink = func(item int) error {
+		written = append(written, item)
+		return nil
+	}
+
+	mockGen.On("GenerateEmbedding", mock.Anything, "content 3").Return([]float32{3}, nil).Once()
+	mockAdapter.On("GetContent", 3).Return("content 3")
+	mockAdapter.On("StoreEmbedding", mock.Anything, mock.Anything).Return()
+
+	result, err := service.GenerateEmbeddings(context.TODO(), []int{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Resumed)
+	assert.Equal(t, 1, result.Succeeded)
+	mockAdapter.AssertCalled(t, "StoreEmbedding", 1, []float32{1})
+	mockAdapter.AssertCalled(t, "StoreEmbedding", 2, []float32{2})
+	mockGen.AssertExpectations(t)
+	assert.Equal(t, []int{1, 2, 3}, written, "resumed items are written before new ones")
+
+	done, err := checkpoint.Load()
+	assert.NoError(t, err)
+	assert.Len(t, done, 3)
+	assert.Equal(t, []float32{3}, done["item-3"])
+	assert.Equal(t, &provenance, checkpoint.Provenance(), "a resumed checkpoint keeps the provenance it was started with")
+}
+
 func TestSteadyRateLimiter_AdjustConcurrency(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -142,3 +269,37 @@ func TestSteadyRateLimiter_AdjustConcurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestCapConcurrency(t *testing.T) {
+	limiter := CapConcurrency(NewSteadyRateLimiter(100, 60*time.Second, 4), 5)
+	assert.Equal(t, 4, limiter.Concurrency())
+	assert.Equal(t, 5, limiter.AdjustConcurrency(120*time.Second))
+	assert.Equal(t, 5, limiter.Concurrency())
+}
+
+func TestTruncatingGenerator_Search(t *testing.T) {
+	vectors := randomVectors(50, 64)
+	truncated := make([][]float32, len(vectors))
+	for i, vector := range vectors {
+		truncated[i] = Truncate(vector, 16)
+		assert.Len(t, truncated[i], 16)
+		assert.InDelta(t, 1, Dot(truncated[i], truncated[i]), 1e-5)
+	}
+	index, err := NewIndex(EncodingFloat32, truncated, 0)
+	assert.NoError(t, err)
+
+	mockGen := new(MockGenerator)
+	mockGen.On("GenerateEmbedding", mock.Anything, "query").Return(vectors[7], nil)
+	query, err := NewTruncatingGenerator(mockGen, 16).GenerateEmbedding(context.Background(), "query")
+	assert.NoError(t, err)
+	matches, err := index.Search(query, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 3) {
+		assert.Equal(t, 7, matches[0].Index)
+		assert.InDelta(t, 1, matches[0].Score, 1e-5)
+	}
+
+	// The full size query the generator wraps cannot be searched.
+	_, err = index.Search(vectors[7], 3)
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+}
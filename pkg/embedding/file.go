@@ -0,0 +1,234 @@
+package embedding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FileFormat marks the header record of an embeddings file.
+const FileFormat = "nvoke-embeddings"
+
+// FileVersion is the latest version of the embeddings file layout written
+// by FileWriter. Version 2 added quantized embeddings; files of float32
+// embeddings are still written as version 1 so older readers accept them.
+const FileVersion = 2
+
+// EmbeddingField is the JSON field holding the embedding of each document.
+const EmbeddingField = "embedding"
+
+// ErrNotEmbeddingsFile is returned for files that are neither an embeddings
+// file nor a legacy JSON array of documents.
+var ErrNotEmbeddingsFile = errors.New("not an embeddings file")
+
+// FileHeader is the first record of an embeddings file. It records how the
+// documents that follow were produced.
+type FileHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Provenance
+	Persona string `json:"persona,omitempty"`
+	// Parser names the corpus format the documents were parsed with and
+	// ParserVersion the version of its parser. Parsers increase their version
+	// whenever a change alters the documents they produce, such as their text
+	// or IDs, so that files parsed by an older version can be told apart.
+	Parser        string `json:"parser,omitempty"`
+	ParserVersion int    `json:"parser_version,omitempty"`
+	// Encoding is how the embeddings of the documents are stored. Float32
+	// embeddings, the default, are arrays of JSON numbers. Float16 and int8
+	// embeddings are base64 strings of the bytes packed by EncodeVector,
+	// taking a fraction of the space at a small loss of precision.
+	Encoding Encoding `json:"encoding,omitempty"`
+}
+
+// encoded reports whether the header stores embeddings in a quantized
+// encoding.
+func (h FileHeader) encoded() bool {
+	return h.Encoding != "" && h.Encoding != EncodingFloat32
+}
+
+// FileWriter writes an embeddings file as newline-delimited JSON: a header
+// record followed by one document per line. Documents can be written as they
+// are embedded, from several goroutines at once.
+type FileWriter struct {
+	mu       sync.Mutex
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+	encoding Encoding
+}
+
+// NewFileWriter writes header to w and returns a writer for the documents,
+// whose embeddings are stored in the header's Encoding.
+func NewFileWriter(w io.Writer, header FileHeader) (*FileWriter, error) {
+	header.Format = FileFormat
+	header.Version = 1
+	if header.encoded() {
+		if _, err := EncodeVector(header.Encoding, nil); err != nil {
+			return nil, err
+		}
+		header.Version = FileVersion
+	} else {
+		header.Encoding = ""
+	}
+	buffered := bufio.NewWriter(w)
+	fw := &FileWriter{writer: buffered, encoder: json.NewEncoder(buffered), encoding: header.Encoding}
+	if err := fw.encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %v", err)
+	}
+	return fw, nil
+}
+
+// Write appends one document.
+func (fw *FileWriter) Write(document interface{}) error {
+	if fw.encoding != "" {
+		var err error
+		if document, err = encodeEmbedding(document, fw.encoding); err != nil {
+			return err
+		}
+	}
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.encoder.Encode(document)
+}
+
+// encodeEmbedding returns the JSON fields of document with its embedding
+// packed in encoding.
+func encodeEmbedding(document interface{}, encoding Encoding) (map[string]json.RawMessage, error) {
+	bytes, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &fields); err != nil {
+		return nil, err
+	}
+	raw, ok := fields[EmbeddingField]
+	if !ok {
+		return fields, nil
+	}
+	var vector []float32
+	if err := json.Unmarshal(raw, &vector); err != nil {
+		return nil, fmt.Errorf("failed to read embedding: %v", err)
+	}
+	data, err := EncodeVector(encoding, vector)
+	if err != nil {
+		return nil, err
+	}
+	fields[EmbeddingField], err = json.Marshal(data)
+	return fields, err
+}
+
+// Flush writes any buffered documents to the underlying writer.
+func (fw *FileWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.writer.Flush()
+}
+
+// FileReader reads the documents of an embeddings file one at a time. It
+// also reads the JSON array of documents written before embeddings files had
+// headers, in which case Header returns nil.
+type FileReader struct {
+	decoder *json.Decoder
+	header  *FileHeader
+	legacy  bool
+}
+
+// NewFileReader reads the header of the embeddings file in r.
+func NewFileReader(r io.Reader) (*FileReader, error) {
+	buffered := bufio.NewReader(r)
+	first, err := firstByte(buffered)
+	if err != nil {
+		return nil, err
+	}
+	fr := &FileReader{decoder: json.NewDecoder(buffered)}
+	switch first {
+	case '[':
+		// Consume the opening bracket so that Next can decode elements.
+		if _, err := fr.decoder.Token(); err != nil {
+			return nil, err
+		}
+		fr.legacy = true
+	case '{':
+		var header FileHeader
+		if err := fr.decoder.Decode(&header); err != nil {
+			return nil, fmt.Errorf("failed to read header: %v", err)
+		}
+		if header.Format != FileFormat {
+			return nil, ErrNotEmbeddingsFile
+		}
+		if header.Version > FileVersion {
+			return nil, fmt.Errorf("unsupported embeddings file version %d", header.Version)
+		}
+		if header.encoded() {
+			if _, err := EncodeVector(header.Encoding, nil); err != nil {
+				return nil, err
+			}
+		}
+		fr.header = &header
+	default:
+		return nil, ErrNotEmbeddingsFile
+	}
+	return fr, nil
+}
+
+// Header returns the file's header, or nil for a legacy JSON array.
+func (fr *FileReader) Header() *FileHeader {
+	return fr.header
+}
+
+// Next decodes the next document into document, returning io.EOF after the
+// last one. Quantized embeddings are restored to float32.
+func (fr *FileReader) Next(document interface{}) error {
+	if fr.legacy && !fr.decoder.More() {
+		if _, err := fr.decoder.Token(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	if fr.header == nil || !fr.header.encoded() {
+		return fr.decoder.Decode(document)
+	}
+	var fields map[string]json.RawMessage
+	if err := fr.decoder.Decode(&fields); err != nil {
+		return err
+	}
+	if raw, ok := fields[EmbeddingField]; ok {
+		var data []byte
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("failed to read embedding: %v", err)
+		}
+		vector, err := DecodeVector(fr.header.Encoding, data)
+		if err != nil {
+			return err
+		}
+		if fields[EmbeddingField], err = json.Marshal(vector); err != nil {
+			return err
+		}
+	}
+	bytes, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, document)
+}
+
+// firstByte peeks at the first byte of r that is not white space.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return 0, ErrNotEmbeddingsFile
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, r.UnreadByte()
+		}
+	}
+}
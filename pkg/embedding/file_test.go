@@ -0,0 +1,136 @@
+package embedding
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fileDocument struct {
+	ID        string    `json:"id"`
+	Embedding []float32 `json:"embedding"`
+}
+
+func readDocuments(t *testing.T, reader *FileReader) []fileDocument {
+	documents := make([]fileDocument, 0)
+	for {
+		var document fileDocument
+		err := reader.Next(&document)
+		if err == io.EOF {
+			return documents
+		}
+		if !assert.NoError(t, err) {
+			return documents
+		}
+		documents = append(documents, document)
+	}
+}
+
+func TestFileWriter_RoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewFileWriter(&buffer, FileHeader{
+		Provenance:    Provenance{Model: "text-embedding-3-small", Dimensions: 2},
+		Persona:       "tao",
+		Parser:        "tao-plaintext",
+		ParserVersion: 1,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Write(fileDocument{ID: "TAO.1", Embedding: []float32{1, 2}}))
+	assert.NoError(t, writer.Write(fileDocument{ID: "TAO.2", Embedding: []float32{3, 4}}))
+	assert.NoError(t, writer.Flush())
+	assert.Equal(t, 3, strings.Count(buffer.String(), "\n"))
+
+	reader, err := NewFileReader(&buffer)
+	assert.NoError(t, err)
+	if assert.NotNil(t, reader.Header()) {
+		assert.Equal(t, FileFormat, reader.Header().Format)
+		assert.Equal(t, "text-embedding-3-small/2", reader.Header().Provenance.String())
+		assert.Equal(t, "tao", reader.Header().Persona)
+	}
+	assert.Equal(t, []fileDocument{
+		{ID: "TAO.1", Embedding: []float32{1, 2}},
+		{ID: "TAO.2", Embedding: []float32{3, 4}},
+	}, readDocuments(t, reader))
+}
+
+func TestFileReader_Legacy(t *testing.T) {
+	reader, err := NewFileReader(strings.NewReader(` [{"id": "TAO.1", "embedding": [1]}, {"id": "TAO.2"}]`))
+	assert.NoError(t, err)
+	assert.Nil(t, reader.Header())
+	assert.Equal(t, []fileDocument{{ID: "TAO.1", Embedding: []float32{1}}, {ID: "TAO.2"}}, readDocuments(t, reader))
+
+	_, err = NewFileReader(strings.NewReader(`{"id": "TAO.1"}`))
+	assert.ErrorIs(t, err, ErrNotEmbeddingsFile)
+}
+
+func TestFileWriter_Concurrent(t *testing.T) {
+	var buffer bytes.Buffer
+	writer, err := NewFileWriter(&buffer, FileHeader{Persona: "tao"})
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, writer.Write(fileDocument{ID: fmt.Sprintf("TAO.%d.%d", i, j)}))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, writer.Flush())
+
+	reader, err := NewFileReader(&buffer)
+	assert.NoError(t, err)
+	assert.Len(t, readDocuments(t, reader), 400)
+}
+
+func TestFileWriter_Encoded(t *testing.T) {
+	vector := randomVectors(1, 256)[0]
+	write := func(encoding Encoding) *bytes.Buffer {
+		var buffer bytes.Buffer
+		writer, err := NewFileWriter(&buffer, FileHeader{Persona: "tao", Encoding: encoding})
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Write(fileDocument{ID: "TAO.1", Embedding: vector}))
+		assert.NoError(t, writer.Write(fileDocument{ID: "TAO.2"}))
+		assert.NoError(t, writer.Flush())
+		return &buffer
+	}
+	full := write(EncodingFloat32)
+
+	for _, tt := range []struct {
+		encoding Encoding
+		delta    float64
+	}{
+		{EncodingFloat16, 0.01},
+		{EncodingInt8, 0.05},
+	} {
+		buffer := write(tt.encoding)
+		assert.Less(t, buffer.Len(), full.Len()/2, "%s is smaller than float32", tt.encoding)
+		reader, err := NewFileReader(buffer)
+		assert.NoError(t, err)
+		if assert.NotNil(t, reader.Header()) {
+			assert.Equal(t, FileVersion, reader.Header().Version)
+			assert.Equal(t, tt.encoding, reader.Header().Encoding)
+		}
+		documents := readDocuments(t, reader)
+		if assert.Len(t, documents, 2) {
+			assert.Equal(t, "TAO.1", documents[0].ID)
+			assert.InDeltaSlice(t, vector, documents[0].Embedding, tt.delta, "%s", tt.encoding)
+			assert.Empty(t, documents[1].Embedding)
+		}
+	}
+
+	reader, err := NewFileReader(full)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reader.Header().Version, "float32 files keep the version older readers accept")
+	assert.Empty(t, reader.Header().Encoding)
+
+	_, err = NewFileWriter(io.Discard, FileHeader{Encoding: EncodingBinary})
+	assert.ErrorIs(t, err, ErrUnstorableEncoding)
+}
@@ -0,0 +1,263 @@
+package embedding
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Encoding selects how vectors are stored in an Index.
+type Encoding string
+
+const (
+	EncodingFloat32 Encoding = "float32"
+	EncodingFloat16 Encoding = "float16"
+	EncodingInt8    Encoding = "int8"
+	EncodingBinary  Encoding = "binary"
+)
+
+var Encodings = []Encoding{EncodingFloat32, EncodingFloat16, EncodingInt8, EncodingBinary}
+
+var ErrUnknownEncoding = errors.New("unknown vector encoding")
+var ErrDimensionMismatch = errors.New("vector dimensions do not match")
+
+// Match is a search result referring to a vector by its position in the index.
+type Match struct {
+	Index int
+	Score float32
+}
+
+// Index is an in-memory exhaustive cosine similarity index. Vectors are
+// normalized and stored in the chosen encoding only, so an index can be built
+// one vector at a time without holding every full precision vector. When
+// rescoring is enabled for an int8 or binary index a half precision copy of
+// each vector is kept as well and the best Rescore candidates from the
+// quantized search are re-ranked with them.
+type Index struct {
+	Encoding Encoding
+	Rescore  int
+
+	dimensions int
+	vectors    [][]float32
+	halves     []Float16Vector
+	int8s      []Int8Vector
+	bits       []BinaryVector
+	rescored   []Float16Vector
+}
+
+// NewIndex builds an index of vectors, which must all have the same number of
+// dimensions. More vectors can be added to it with Add.
+func NewIndex(encoding Encoding, vectors [][]float32, rescore int) (*Index, error) {
+	if !slices.Contains(Encodings, encoding) {
+		return nil, ErrUnknownEncoding
+	}
+	index := &Index{Encoding: encoding, Rescore: rescore}
+	for _, vector := range vectors {
+		if err := index.Add(vector); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// Add normalizes and encodes vector and appends it to the index. Its position
+// is the index of the Matches it is found by. The first vector added sets the
+// dimensions of the index.
+func (ix *Index) Add(vector []float32) error {
+	if ix.Len() == 0 {
+		ix.dimensions = len(vector)
+	} else if len(vector) != ix.dimensions {
+		return fmt.Errorf("vector %d has %d dimensions, expected %d: %w", ix.Len(), len(vector), ix.dimensions, ErrDimensionMismatch)
+	}
+	normalized := Normalize(vector)
+	switch ix.Encoding {
+	case EncodingFloat32:
+		ix.vectors = append(ix.vectors, normalized)
+	case EncodingFloat16:
+		ix.halves = append(ix.halves, QuantizeFloat16(normalized))
+	case EncodingInt8:
+		ix.int8s = append(ix.int8s, QuantizeInt8(normalized))
+	case EncodingBinary:
+		ix.bits = append(ix.bits, QuantizeBinary(normalized))
+	default:
+		return ErrUnknownEncoding
+	}
+	if ix.rescoring() {
+		ix.rescored = append(ix.rescored, QuantizeFloat16(normalized))
+	}
+	return nil
+}
+
+// rescoring reports whether searches re-rank their candidates with the half
+// precision copies of the vectors, which only improves on int8 and binary
+// scores.
+func (ix *Index) rescoring() bool {
+	return ix.Rescore > 0 && (ix.Encoding == EncodingInt8 || ix.Encoding == EncodingBinary)
+}
+
+// Len is the number of vectors in the index.
+func (ix *Index) Len() int {
+	return max(max(len(ix.vectors), len(ix.halves)), max(len(ix.int8s), len(ix.bits)))
+}
+
+// Size is the number of bytes used by the encoded vectors, excluding any half
+// precision copies kept for rescoring.
+func (ix *Index) Size() int {
+	size := 0
+	switch ix.Encoding {
+	case EncodingFloat32:
+		for _, vector := range ix.vectors {
+			size += len(vector) * 4
+		}
+	case EncodingFloat16:
+		for _, vector := range ix.halves {
+			size += vector.Size()
+		}
+	case EncodingInt8:
+		for _, vector := range ix.int8s {
+			size += vector.Size()
+		}
+	case EncodingBinary:
+		for _, vector := range ix.bits {
+			size += vector.Size()
+		}
+	}
+	return size
+}
+
+// Dimensions is the length of the vectors in the index.
+func (ix *Index) Dimensions() int {
+	return ix.dimensions
+}
+
+// Search returns the k most similar vectors to query, best first. Binary
+// indexes rank by Hamming distance, so their scores are the fraction of
+// matching sign bits unless the results are rescored. The query must have as
+// many dimensions as the indexed vectors.
+func (ix *Index) Search(query []float32, k int) ([]Match, error) {
+	if ix.Len() > 0 && len(query) != ix.dimensions {
+		return nil, fmt.Errorf("query has %d dimensions, the index has %d: %w", len(query), ix.dimensions, ErrDimensionMismatch)
+	}
+	query = Normalize(query)
+	candidates := k
+	if ix.rescoring() && ix.Rescore > k {
+		candidates = ix.Rescore
+	}
+
+	var matches []Match
+	switch ix.Encoding {
+	case EncodingFloat32:
+		matches = score(len(ix.vectors), func(i int) float32 { return Dot(query, ix.vectors[i]) })
+	case EncodingFloat16:
+		matches = score(len(ix.halves), func(i int) float32 { return ix.halves[i].Dot(query) })
+	case EncodingInt8:
+		q := QuantizeInt8(query)
+		matches = score(len(ix.int8s), func(i int) float32 { return ix.int8s[i].Dot(q) })
+	case EncodingBinary:
+		q := QuantizeBinary(query)
+		dimensions := float32(len(query))
+		matches = score(len(ix.bits), func(i int) float32 { return 1 - float32(ix.bits[i].Hamming(q))/dimensions })
+	}
+	matches = top(matches, candidates)
+
+	if ix.rescoring() {
+		for i := range matches {
+			matches[i].Score = ix.rescored[matches[i].Index].Dot(query)
+		}
+		matches = top(matches, k)
+	}
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func score(n int, similarity func(i int) float32) []Match {
+	matches := make([]Match, n)
+	for i := range matches {
+		matches[i] = Match{Index: i, Score: similarity(i)}
+	}
+	return matches
+}
+
+func top(matches []Match, k int) []Match {
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].Score > matches[b].Score })
+	if len(matches) > k {
+		return matches[:k]
+	}
+	return matches
+}
+
+// Recall is the fraction of the expected matches that were found.
+func Recall(expected, actual []Match) float64 {
+	if len(expected) == 0 {
+		return 1
+	}
+	found := make(map[int]bool, len(actual))
+	for _, match := range actual {
+		found[match.Index] = true
+	}
+	hits := 0
+	for _, match := range expected {
+		if found[match.Index] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(expected))
+}
+
+// Dot is the dot product of two vectors of equal length.
+func Dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// Normalize returns a unit length copy of vector.
+func Normalize(vector []float32) []float32 {
+	norm := math.Sqrt(float64(Dot(vector, vector)))
+	normalized := make([]float32, len(vector))
+	if norm == 0 {
+		return normalized
+	}
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// EvaluateRecall returns the mean recall@k of candidate measured against the
+// exact full precision results for each query.
+func EvaluateRecall(exact, candidate *Index, queries [][]float32, k int) (float64, error) {
+	if len(queries) == 0 {
+		return 1, nil
+	}
+	total := 0.0
+	for _, query := range queries {
+		expected, err := exact.Search(query, k)
+		if err != nil {
+			return 0, err
+		}
+		actual, err := candidate.Search(query, k)
+		if err != nil {
+			return 0, err
+		}
+		total += Recall(expected, actual)
+	}
+	return total / float64(len(queries)), nil
+}
+
+// Truncate keeps the first dimensions components of vector and renormalizes
+// the result. Models trained with Matryoshka representation learning, such as
+// text-embedding-3, front-load information so the prefix remains a useful
+// embedding. Vectors that are already short enough are returned unchanged.
+func Truncate(vector []float32, dimensions int) []float32 {
+	if dimensions <= 0 || dimensions >= len(vector) {
+		return vector
+	}
+	return Normalize(vector[:dimensions])
+}
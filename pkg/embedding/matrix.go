@@ -0,0 +1,210 @@
+package embedding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matrix formats understood by WriteMatrix and ReadMatrix.
+const (
+	// MatrixNPY is a NumPy .npy file of little-endian float32, or float16.
+	MatrixNPY = "npy"
+	// MatrixFloat32 is raw little-endian float32 values, row after row.
+	MatrixFloat32 = "f32bin"
+)
+
+var ErrUnknownMatrixFormat = errors.New("unknown matrix format")
+
+// npyMagic starts every .npy file, followed by the format version.
+const npyMagic = "\x93NUMPY"
+
+// MatrixColumns returns the length shared by vectors, or an error naming the
+// first row whose length differs.
+func MatrixColumns(vectors [][]float32) (int, error) {
+	columns := 0
+	if len(vectors) > 0 {
+		columns = len(vectors[0])
+	}
+	for i, vector := range vectors {
+		if len(vector) != columns {
+			return 0, fmt.Errorf("row %d has %d columns, expected %d", i, len(vector), columns)
+		}
+	}
+	return columns, nil
+}
+
+// npyTypes are the .npy data types of the encodings a matrix can be
+// written in.
+var npyTypes = map[Encoding]string{
+	EncodingFloat32: "<f4",
+	EncodingFloat16: "<f2",
+}
+
+// WriteMatrix writes vectors, which must all have the same length, as rows
+// of a matrix in format. A .npy matrix can hold float32 or float16 values,
+// a raw matrix only float32.
+func WriteMatrix(w io.Writer, format string, encoding Encoding, vectors [][]float32) error {
+	columns, err := MatrixColumns(vectors)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(w)
+	switch format {
+	case MatrixNPY:
+		descr, ok := npyTypes[encoding]
+		if !ok {
+			return fmt.Errorf("%s matrices hold float32 or float16 values, not %s: %w", format, encoding, ErrUnknownEncoding)
+		}
+		if err := writeNPYHeader(buffered, descr, len(vectors), columns); err != nil {
+			return err
+		}
+	case MatrixFloat32:
+		if encoding != EncodingFloat32 {
+			return fmt.Errorf("%s matrices hold float32 values, not %s: %w", format, encoding, ErrUnknownEncoding)
+		}
+	default:
+		return fmt.Errorf("%q: %w", format, ErrUnknownMatrixFormat)
+	}
+	for _, vector := range vectors {
+		row, err := EncodeVector(encoding, vector)
+		if err != nil {
+			return err
+		}
+		if _, err := buffered.Write(row); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// writeNPYHeader writes a version 1.0 header, padded so that the data
+// starts on a 64 byte boundary as NumPy does.
+func writeNPYHeader(w io.Writer, descr string, rows int, columns int) error {
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }", descr, rows, columns)
+	prefix := len(npyMagic) + 2 + 2
+	padding := 64 - (prefix+len(header)+1)%64
+	if padding == 64 {
+		padding = 0
+	}
+	header += strings.Repeat(" ", padding) + "\n"
+	var preamble bytes.Buffer
+	preamble.WriteString(npyMagic)
+	preamble.Write([]byte{1, 0})
+	binary.Write(&preamble, binary.LittleEndian, uint16(len(header)))
+	preamble.WriteString(header)
+	_, err := w.Write(preamble.Bytes())
+	return err
+}
+
+var (
+	npyDescr = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	npyOrder = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape = regexp.MustCompile(`'shape':\s*\((\d+),\s*(\d*)\)`)
+)
+
+// ReadMatrix reads the rows of a matrix in format. A raw float32 matrix does
+// not record its shape, so rows must be given; it is ignored for .npy files.
+func ReadMatrix(r io.Reader, format string, rows int) ([][]float32, error) {
+	buffered := bufio.NewReader(r)
+	columns := 0
+	encoding := EncodingFloat32
+	switch format {
+	case MatrixNPY:
+		var err error
+		if encoding, rows, columns, err = readNPYHeader(buffered); err != nil {
+			return nil, err
+		}
+	case MatrixFloat32:
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, err
+		}
+		if rows == 0 || len(data)%(4*rows) != 0 {
+			return nil, fmt.Errorf("%d bytes do not hold %d rows of float32", len(data), rows)
+		}
+		columns = len(data) / 4 / rows
+		buffered = bufio.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("%q: %w", format, ErrUnknownMatrixFormat)
+	}
+
+	vectors := make([][]float32, rows)
+	width := 4
+	if encoding == EncodingFloat16 {
+		width = 2
+	}
+	row := make([]byte, width*columns)
+	for i := range vectors {
+		if _, err := io.ReadFull(buffered, row); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		vector, err := DecodeVector(encoding, row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i, err)
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// readNPYHeader reads the encoding and shape of a two dimensional, C
+// ordered, little-endian float32 or float16 .npy file.
+func readNPYHeader(r io.Reader) (Encoding, int, int, error) {
+	preamble := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, preamble); err != nil {
+		return "", 0, 0, err
+	}
+	if string(preamble[:len(npyMagic)]) != npyMagic {
+		return "", 0, 0, errors.New("not a .npy file")
+	}
+	var length int
+	switch major := preamble[len(npyMagic)]; major {
+	case 1:
+		var size uint16
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return "", 0, 0, err
+		}
+		length = int(size)
+	case 2, 3:
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return "", 0, 0, err
+		}
+		length = int(size)
+	default:
+		return "", 0, 0, fmt.Errorf("unsupported .npy version %d", major)
+	}
+	header := make([]byte, length)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, 0, err
+	}
+
+	var encoding Encoding
+	if descr := npyDescr.FindSubmatch(header); descr != nil {
+		for candidate, name := range npyTypes {
+			if string(descr[1]) == name {
+				encoding = candidate
+			}
+		}
+	}
+	if encoding == "" {
+		return "", 0, 0, fmt.Errorf("unsupported .npy data type, expected '<f4' or '<f2'")
+	}
+	if order := npyOrder.FindSubmatch(header); order != nil && string(order[1]) == "True" {
+		return "", 0, 0, errors.New("unsupported Fortran ordered .npy file")
+	}
+	shape := npyShape.FindSubmatch(header)
+	if shape == nil || len(shape[2]) == 0 {
+		return "", 0, 0, errors.New("unsupported .npy shape, expected two dimensions")
+	}
+	rows, _ := strconv.Atoi(string(shape[1]))
+	columns, _ := strconv.Atoi(string(shape[2]))
+	return encoding, rows, columns, nil
+}
@@ -0,0 +1,74 @@
+package embedding
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrix_RoundTrip(t *testing.T) {
+	vectors := [][]float32{{1, -0.5, 3}, {0, 2.25, -1}}
+	for _, format := range []string{MatrixNPY, MatrixFloat32} {
+		var buffer bytes.Buffer
+		assert.NoError(t, WriteMatrix(&buffer, format, EncodingFloat32, vectors))
+		if format == MatrixNPY {
+			assert.Zero(t, (buffer.Len()-2*3*4)%64, "data starts on a 64 byte boundary")
+			assert.Contains(t, buffer.String(), "'shape': (2, 3)")
+		}
+		read, err := ReadMatrix(&buffer, format, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, vectors, read)
+	}
+
+	assert.Error(t, WriteMatrix(io.Discard, MatrixNPY, EncodingFloat32, [][]float32{{1}, {1, 2}}))
+	assert.ErrorIs(t, WriteMatrix(io.Discard, "csv", EncodingFloat32, vectors), ErrUnknownMatrixFormat)
+}
+
+func TestMatrix_Float16(t *testing.T) {
+	vectors := [][]float32{{1, -0.5, 3}, {0, 2.25, -1}}
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteMatrix(&buffer, MatrixNPY, EncodingFloat16, vectors))
+	assert.Contains(t, buffer.String(), "'descr': '<f2'")
+	assert.Zero(t, (buffer.Len()-2*3*2)%64, "data starts on a 64 byte boundary")
+	read, err := ReadMatrix(&buffer, MatrixNPY, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, vectors, read, "these values are exact in half precision")
+
+	assert.ErrorIs(t, WriteMatrix(io.Discard, MatrixFloat32, EncodingFloat16, vectors), ErrUnknownEncoding)
+	assert.ErrorIs(t, WriteMatrix(io.Discard, MatrixNPY, EncodingInt8, vectors), ErrUnknownEncoding)
+}
+
+func TestReadMatrix_Invalid(t *testing.T) {
+	var npy bytes.Buffer
+	assert.NoError(t, WriteMatrix(&npy, MatrixNPY, EncodingFloat32, [][]float32{{1, 2}}))
+	int32s := strings.Replace(npy.String(), "<f4", "<i4", 1)
+
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		rows   int
+	}{
+		{"bad magic", MatrixNPY, "NUMPY\x01\x00" + npy.String()[8:], 0},
+		{"bad descr", MatrixNPY, int32s, 0},
+		{"truncated npy", MatrixNPY, npy.String()[:npy.Len()-1], 0},
+		{"partial float32", MatrixFloat32, "\x00\x00\x80\x3f\x00\x00", 1},
+		{"float32 rows", MatrixFloat32, strings.Repeat("\x00", 12), 2},
+	}
+	for _, test := range tests {
+		_, err := ReadMatrix(strings.NewReader(test.data), test.format, test.rows)
+		assert.Error(t, err, test.name)
+	}
+}
+
+func TestMatrixColumns(t *testing.T) {
+	columns, err := MatrixColumns([][]float32{{1, 2}, {3, 4}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, columns)
+
+	_, err = MatrixColumns([][]float32{{1, 2}, {3}})
+	assert.EqualError(t, err, "row 1 has 1 columns, expected 2")
+}
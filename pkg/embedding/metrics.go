@@ -0,0 +1,97 @@
+package embedding
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is an Observer that keeps running counters. It implements
+// expvar.Var so it can be published alongside other variables.
+type Metrics struct {
+	started      int64
+	succeeded    int64
+	failed       int64
+	inFlight     int64
+	maxInFlight  int64
+	batches      int64
+	latencyNanos int64
+	maxLatency   int64
+}
+
+// MetricsSnapshot is a point in time copy of Metrics.
+type MetricsSnapshot struct {
+	Started        int64         `json:"started"`
+	Succeeded      int64         `json:"succeeded"`
+	Failed         int64         `json:"failed"`
+	InFlight       int64         `json:"in_flight"`
+	MaxInFlight    int64         `json:"max_in_flight"`
+	Batches        int64         `json:"batches"`
+	AverageLatency time.Duration `json:"average_latency_ns"`
+	MaxLatency     time.Duration `json:"max_latency_ns"`
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+func (m *Metrics) ItemStarted(event ItemEvent) {
+	atomic.AddInt64(&m.started, 1)
+	inFlight := atomic.AddInt64(&m.inFlight, 1)
+	storeMax(&m.maxInFlight, inFlight)
+}
+
+func (m *Metrics) ItemSucceeded(event ItemEvent) {
+	atomic.AddInt64(&m.succeeded, 1)
+	m.finish(event)
+}
+
+func (m *Metrics) ItemFailed(event ItemEvent) {
+	atomic.AddInt64(&m.failed, 1)
+	m.finish(event)
+}
+
+func (m *Metrics) BatchCompleted(event BatchEvent) {
+	atomic.AddInt64(&m.batches, 1)
+}
+
+func (m *Metrics) finish(event ItemEvent) {
+	atomic.AddInt64(&m.inFlight, -1)
+	atomic.AddInt64(&m.latencyNanos, int64(event.Latency))
+	storeMax(&m.maxLatency, int64(event.Latency))
+}
+
+// Snapshot returns the current value of every counter.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snapshot := MetricsSnapshot{
+		Started:     atomic.LoadInt64(&m.started),
+		Succeeded:   atomic.LoadInt64(&m.succeeded),
+		Failed:      atomic.LoadInt64(&m.failed),
+		InFlight:    atomic.LoadInt64(&m.inFlight),
+		MaxInFlight: atomic.LoadInt64(&m.maxInFlight),
+		Batches:     atomic.LoadInt64(&m.batches),
+		MaxLatency:  time.Duration(atomic.LoadInt64(&m.maxLatency)),
+	}
+	if finished := snapshot.Succeeded + snapshot.Failed; finished > 0 {
+		snapshot.AverageLatency = time.Duration(atomic.LoadInt64(&m.latencyNanos) / finished)
+	}
+	return snapshot
+}
+
+// String renders the snapshot as JSON for expvar.
+func (m *Metrics) String() string {
+	bytes, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(bytes)
+}
+
+func storeMax(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if value <= current || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
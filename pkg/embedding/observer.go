@@ -0,0 +1,121 @@
+package embedding
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// ItemEvent describes a single item moving through the embedding pipeline.
+// Completed and Total count items across the whole run, including those
+// restored from a checkpoint.
+type ItemEvent struct {
+	Index     int
+	Latency   time.Duration
+	Err       error
+	InFlight  int
+	Completed int
+	Total     int
+}
+
+// BatchEvent describes a batch of requests that has finished.
+type BatchEvent struct {
+	Succeeded int
+	Failed    int
+	Workers   int
+	Elapsed   time.Duration
+	Completed int
+	Total     int
+}
+
+// Observer receives progress notifications from a Service. Implementations
+// must be safe for concurrent use since items are processed by several workers.
+type Observer interface {
+	ItemStarted(event ItemEvent)
+	ItemSucceeded(event ItemEvent)
+	ItemFailed(event ItemEvent)
+	BatchCompleted(event BatchEvent)
+}
+
+// Observers fans notifications out to several observers.
+type Observers []Observer
+
+func (o Observers) ItemStarted(event ItemEvent) {
+	for _, observer := range o {
+		observer.ItemStarted(event)
+	}
+}
+
+func (o Observers) ItemSucceeded(event ItemEvent) {
+	for _, observer := range o {
+		observer.ItemSucceeded(event)
+	}
+}
+
+func (o Observers) ItemFailed(event ItemEvent) {
+	for _, observer := range o {
+		observer.ItemFailed(event)
+	}
+}
+
+func (o Observers) BatchCompleted(event BatchEvent) {
+	for _, observer := range o {
+		observer.BatchCompleted(event)
+	}
+}
+
+// LogObserver logs a line for every completed batch.
+type LogObserver struct{}
+
+func (LogObserver) ItemStarted(event ItemEvent)   {}
+func (LogObserver) ItemSucceeded(event ItemEvent) {}
+func (LogObserver) ItemFailed(event ItemEvent)    {}
+
+func (LogObserver) BatchCompleted(event BatchEvent) {
+	log.Printf("Processed %d items (%d failed) in %s with %d workers. Total processed %d of %d\n",
+		event.Succeeded, event.Failed, event.Elapsed, event.Workers, event.Completed, event.Total)
+}
+
+// ObservedGenerator reports every call to the wrapped Generator as an item,
+// which lets a long running server track query embedding latency.
+type ObservedGenerator struct {
+	Generator Generator
+	Observer  Observer
+	inFlight  int64
+	completed int64
+}
+
+func NewObservedGenerator(generator Generator, observer Observer) *ObservedGenerator {
+	return &ObservedGenerator{
+		Generator: generator,
+		Observer:  observer,
+	}
+}
+
+func (og *ObservedGenerator) GenerateEmbedding(ctx context.Context, content string) ([]float32, error) {
+	event := ItemEvent{
+		InFlight:  int(atomic.AddInt64(&og.inFlight, 1)),
+		Completed: int(atomic.LoadInt64(&og.completed)),
+	}
+	og.Observer.ItemStarted(event)
+
+	start := time.Now()
+	embedding, err := og.Generator.GenerateEmbedding(ctx, content)
+	event.Latency = time.Since(start)
+	event.InFlight = int(atomic.AddInt64(&og.inFlight, -1))
+	event.Completed = int(atomic.AddInt64(&og.completed, 1))
+	event.Err = err
+	if err != nil {
+		og.Observer.ItemFailed(event)
+	} else {
+		og.Observer.ItemSucceeded(event)
+	}
+	return embedding, err
+}
+
+// Provenance reports the provenance of the wrapped generator.
+func (og *ObservedGenerator) Provenance() Provenance {
+	provenance, _ := Describe(og.Generator)
+	return provenance
+}
@@ -0,0 +1,102 @@
+package embedding
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressBar is an Observer that draws a single updating line on a terminal
+// with throughput and an estimated time to completion.
+type ProgressBar struct {
+	writer   io.Writer
+	width    int
+	interval time.Duration
+
+	mu        sync.Mutex
+	start     time.Time
+	initial   int
+	last      time.Time
+	completed int
+	total     int
+	inFlight  int
+}
+
+func NewProgressBar(writer io.Writer) *ProgressBar {
+	return &ProgressBar{
+		writer:   writer,
+		width:    30,
+		interval: 200 * time.Millisecond,
+	}
+}
+
+func (p *ProgressBar) ItemStarted(event ItemEvent) {
+	p.update(event.Completed, event.Total, event.InFlight, false)
+}
+
+func (p *ProgressBar) ItemSucceeded(event ItemEvent) {
+	p.update(event.Completed, event.Total, event.InFlight, false)
+}
+
+func (p *ProgressBar) ItemFailed(event ItemEvent) {
+	p.update(event.Completed, event.Total, event.InFlight, false)
+}
+
+func (p *ProgressBar) BatchCompleted(event BatchEvent) {
+	p.update(event.Completed, event.Total, 0, true)
+}
+
+// Finish draws the final state and moves the cursor to the next line.
+func (p *ProgressBar) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.start.IsZero() {
+		return
+	}
+	p.render()
+	fmt.Fprintln(p.writer)
+}
+
+func (p *ProgressBar) update(completed, total, inFlight int, force bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.start.IsZero() {
+		p.start = now
+		p.initial = completed
+	}
+	// Events from different workers can arrive out of order.
+	p.completed = max(p.completed, completed)
+	p.total = total
+	p.inFlight = inFlight
+	if !force && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+	p.render()
+}
+
+func (p *ProgressBar) render() {
+	fraction := 1.0
+	if p.total > 0 {
+		fraction = float64(p.completed) / float64(p.total)
+	}
+	filled := int(fraction * float64(p.width))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", p.width-filled)
+
+	elapsed := time.Since(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.completed-p.initial) / elapsed.Seconds()
+	}
+	eta := "--"
+	if rate > 0 {
+		remaining := time.Duration(float64(p.total-p.completed) / rate * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+	fmt.Fprintf(p.writer, "\r[%s] %d/%d %5.1f%% %.1f items/s ETA %s in flight %d ",
+		bar, p.completed, p.total, fraction*100, rate, eta, p.inFlight)
+}
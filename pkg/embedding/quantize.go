@@ -0,0 +1,242 @@
+package embedding
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// ErrUnstorableEncoding is returned when storing vectors in an encoding they
+// cannot be restored from, such as binary, which keeps only their signs.
+var ErrUnstorableEncoding = errors.New("vector encoding cannot be stored")
+
+// Int8Vector is a vector scalar quantized to int8 with a per-vector scale, so
+// that value[i] ≈ Values[i] * Scale.
+type Int8Vector struct {
+	Scale  float32 `json:"scale"`
+	Values []int8  `json:"values"`
+}
+
+// QuantizeInt8 maps the largest magnitude component of vector onto ±127.
+func QuantizeInt8(vector []float32) Int8Vector {
+	var maxAbs float32
+	for _, v := range vector {
+		maxAbs = float32(math.Max(float64(maxAbs), math.Abs(float64(v))))
+	}
+	quantized := Int8Vector{Values: make([]int8, len(vector))}
+	if maxAbs == 0 {
+		return quantized
+	}
+	quantized.Scale = maxAbs / 127
+	for i, v := range vector {
+		quantized.Values[i] = int8(math.Round(float64(v / quantized.Scale)))
+	}
+	return quantized
+}
+
+// Dot approximates the dot product of two quantized vectors.
+func (v Int8Vector) Dot(other Int8Vector) float32 {
+	var sum int32
+	for i, value := range v.Values {
+		sum += int32(value) * int32(other.Values[i])
+	}
+	return float32(sum) * v.Scale * other.Scale
+}
+
+// Float32 returns the dequantized vector.
+func (v Int8Vector) Float32() []float32 {
+	vector := make([]float32, len(v.Values))
+	for i, value := range v.Values {
+		vector[i] = float32(value) * v.Scale
+	}
+	return vector
+}
+
+// Size is the number of bytes needed to store the vector.
+func (v Int8Vector) Size() int {
+	return len(v.Values) + 4
+}
+
+// Float16Vector stores each component as an IEEE 754 half precision float.
+type Float16Vector []uint16
+
+func QuantizeFloat16(vector []float32) Float16Vector {
+	quantized := make(Float16Vector, len(vector))
+	for i, v := range vector {
+		quantized[i] = toFloat16(v)
+	}
+	return quantized
+}
+
+// Dot computes the dot product with a full precision vector.
+func (v Float16Vector) Dot(other []float32) float32 {
+	var sum float32
+	for i, value := range v {
+		sum += fromFloat16(value) * other[i]
+	}
+	return sum
+}
+
+func (v Float16Vector) Float32() []float32 {
+	vector := make([]float32, len(v))
+	for i, value := range v {
+		vector[i] = fromFloat16(value)
+	}
+	return vector
+}
+
+func (v Float16Vector) Size() int {
+	return len(v) * 2
+}
+
+// BinaryVector keeps only the sign of each component, one bit per dimension.
+type BinaryVector []uint64
+
+func QuantizeBinary(vector []float32) BinaryVector {
+	quantized := make(BinaryVector, (len(vector)+63)/64)
+	for i, v := range vector {
+		if v > 0 {
+			quantized[i/64] |= 1 << uint(i%64)
+		}
+	}
+	return quantized
+}
+
+// Hamming counts the dimensions whose signs differ.
+func (v BinaryVector) Hamming(other BinaryVector) int {
+	distance := 0
+	for i, word := range v {
+		distance += bits.OnesCount64(word ^ other[i])
+	}
+	return distance
+}
+
+func (v BinaryVector) Size() int {
+	return len(v) * 8
+}
+
+// toFloat16 converts to half precision, rounding to nearest.
+func toFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	rawExp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if rawExp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+	exp := rawExp - 127 + 15
+	switch {
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		// Subnormal half, or too small to represent at all.
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		return sign | uint16((mant+(1<<(shift-1)))>>shift)
+	}
+	half := sign | uint16(exp<<10) | uint16(mant>>13)
+	if mant&0x1000 != 0 {
+		// A carry out of the mantissa correctly rounds up into the exponent.
+		half++
+	}
+	return half
+}
+
+func fromFloat16(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// EncodeVector packs vector into the little-endian bytes of encoding. Int8
+// vectors start with their float32 scale.
+func EncodeVector(encoding Encoding, vector []float32) ([]byte, error) {
+	switch encoding {
+	case EncodingFloat32:
+		data := make([]byte, 4*len(vector))
+		for i, v := range vector {
+			binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+		}
+		return data, nil
+	case EncodingFloat16:
+		data := make([]byte, 2*len(vector))
+		for i, v := range QuantizeFloat16(vector) {
+			binary.LittleEndian.PutUint16(data[2*i:], v)
+		}
+		return data, nil
+	case EncodingInt8:
+		quantized := QuantizeInt8(vector)
+		data := make([]byte, 4+len(vector))
+		binary.LittleEndian.PutUint32(data, math.Float32bits(quantized.Scale))
+		for i, v := range quantized.Values {
+			data[4+i] = byte(v)
+		}
+		return data, nil
+	case EncodingBinary:
+		return nil, fmt.Errorf("%s: %w", encoding, ErrUnstorableEncoding)
+	}
+	return nil, fmt.Errorf("%q: %w", encoding, ErrUnknownEncoding)
+}
+
+// DecodeVector restores a vector packed by EncodeVector.
+func DecodeVector(encoding Encoding, data []byte) ([]float32, error) {
+	switch encoding {
+	case EncodingFloat32:
+		if len(data)%4 != 0 {
+			return nil, fmt.Errorf("%d bytes do not hold float32 values", len(data))
+		}
+		vector := make([]float32, len(data)/4)
+		for i := range vector {
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+		}
+		return vector, nil
+	case EncodingFloat16:
+		if len(data)%2 != 0 {
+			return nil, fmt.Errorf("%d bytes do not hold float16 values", len(data))
+		}
+		vector := make(Float16Vector, len(data)/2)
+		for i := range vector {
+			vector[i] = binary.LittleEndian.Uint16(data[2*i:])
+		}
+		return vector.Float32(), nil
+	case EncodingInt8:
+		if len(data) < 4 {
+			return nil, fmt.Errorf("%d bytes do not hold an int8 scale", len(data))
+		}
+		quantized := Int8Vector{
+			Scale:  math.Float32frombits(binary.LittleEndian.Uint32(data)),
+			Values: make([]int8, len(data)-4),
+		}
+		for i := range quantized.Values {
+			quantized.Values[i] = int8(data[4+i])
+		}
+		return quantized.Float32(), nil
+	case EncodingBinary:
+		return nil, fmt.Errorf("%s: %w", encoding, ErrUnstorableEncoding)
+	}
+	return nil, fmt.Errorf("%q: %w", encoding, ErrUnknownEncoding)
+}
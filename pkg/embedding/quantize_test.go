@@ -0,0 +1,142 @@
+package embedding
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomVectors(n, dimensions int) [][]float32 {
+	random := rand.New(rand.NewSource(1))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dimensions)
+		for j := range vectors[i] {
+			vectors[i][j] = float32(random.NormFloat64())
+		}
+	}
+	return vectors
+}
+
+func TestFloat16_RoundTrip(t *testing.T) {
+	tests := []float32{0, 1, -1, 0.5, 65504, 1e-5, -0.333333}
+	for _, value := range tests {
+		delta := math.Max(math.Abs(float64(value))*1e-3, 1e-7)
+		assert.InDelta(t, value, fromFloat16(toFloat16(value)), delta, "value %v", value)
+	}
+	assert.True(t, math.IsInf(float64(fromFloat16(toFloat16(float32(math.Inf(-1))))), -1))
+	assert.Equal(t, uint16(0x3c00), toFloat16(1))
+	assert.Equal(t, uint16(0x7c00), toFloat16(1e6))
+	assert.True(t, math.IsNaN(float64(fromFloat16(toFloat16(float32(math.NaN()))))))
+}
+
+func TestQuantizeInt8(t *testing.T) {
+	vector := []float32{0.5, -1, 0.25, 0}
+	quantized := QuantizeInt8(vector)
+	assert.Equal(t, []int8{64, -127, 32, 0}, quantized.Values)
+	for i, value := range quantized.Float32() {
+		assert.InDelta(t, vector[i], value, float64(quantized.Scale))
+	}
+	assert.InDelta(t, Dot(vector, vector), quantized.Dot(quantized), 0.01)
+}
+
+func TestQuantizeBinary(t *testing.T) {
+	a := QuantizeBinary([]float32{1, -1, 1, -1})
+	b := QuantizeBinary([]float32{1, 1, -1, -1})
+	assert.Equal(t, BinaryVector{0b0101}, a)
+	assert.Equal(t, 2, a.Hamming(b))
+	assert.Equal(t, 8, a.Size())
+}
+
+func TestIndex_Recall(t *testing.T) {
+	vectors := randomVectors(500, 64)
+	queries := randomVectors(20, 64)
+
+	exact, err := NewIndex(EncodingFloat32, vectors, 0)
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		encoding  Encoding
+		rescore   int
+		minRecall float64
+	}{
+		{EncodingFloat16, 0, 0.99},
+		{EncodingInt8, 0, 0.85},
+		{EncodingInt8, 50, 0.99},
+		{EncodingBinary, 100, 0.8},
+	} {
+		index, err := NewIndex(tt.encoding, vectors, tt.rescore)
+		assert.NoError(t, err)
+		recall, err := EvaluateRecall(exact, index, queries, 10)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, recall, tt.minRecall, "%s rescore %d", tt.encoding, tt.rescore)
+		assert.Less(t, index.Size(), exact.Size())
+	}
+
+	_, err = NewIndex("float8", vectors, 0)
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+}
+
+func TestIndex_Dimensions(t *testing.T) {
+	index, err := NewIndex(EncodingFloat32, randomVectors(10, 8), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, index.Dimensions())
+
+	_, err = index.Search(randomVectors(1, 16)[0], 5)
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+	_, err = index.Search(randomVectors(1, 4)[0], 5)
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+
+	_, err = NewIndex(EncodingInt8, [][]float32{{1, 2}, {1, 2, 3}}, 0)
+	assert.ErrorIs(t, err, ErrDimensionMismatch)
+}
+
+func TestEncodeVector(t *testing.T) {
+	vector := []float32{0.5, -1, 0.25, 0}
+	for _, tt := range []struct {
+		encoding Encoding
+		size     int
+	}{
+		{EncodingFloat32, 16},
+		{EncodingFloat16, 8},
+		{EncodingInt8, 8},
+	} {
+		data, err := EncodeVector(tt.encoding, vector)
+		assert.NoError(t, err)
+		assert.Len(t, data, tt.size, "%s", tt.encoding)
+		decoded, err := DecodeVector(tt.encoding, data)
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, vector, decoded, 0.01, "%s", tt.encoding)
+	}
+
+	_, err := EncodeVector(EncodingBinary, vector)
+	assert.ErrorIs(t, err, ErrUnstorableEncoding)
+	_, err = DecodeVector(EncodingFloat16, []byte{1, 2, 3})
+	assert.Error(t, err)
+	_, err = EncodeVector("float8", vector)
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+}
+
+func TestIndex_Add(t *testing.T) {
+	vectors := randomVectors(100, 32)
+	built, err := NewIndex(EncodingInt8, vectors, 20)
+	assert.NoError(t, err)
+	index, err := NewIndex(EncodingInt8, nil, 20)
+	assert.NoError(t, err)
+	for _, vector := range vectors {
+		assert.NoError(t, index.Add(vector))
+	}
+	assert.Equal(t, built.Len(), index.Len())
+	assert.Equal(t, 32, index.Dimensions())
+
+	query := randomVectors(1, 32)[0]
+	expected, err := built.Search(query, 5)
+	assert.NoError(t, err)
+	actual, err := index.Search(query, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	assert.ErrorIs(t, index.Add(make([]float32, 16)), ErrDimensionMismatch)
+}
@@ -0,0 +1,276 @@
+// Package scripture parses Bible references such as "John 3:16" or
+// "1 Cor 13; 15:3-8".
+package scripture
+
+import (
+	"errors"
+	"fmt"
+	"nvoke/pkg/bible"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidReference = errors.New("invalid scripture reference")
+
+// Reference is a passage within one book. A zero StartVerse starts at the
+// beginning of StartChapter and a zero EndVerse runs to the end of EndChapter,
+// so "1 Cor 13" is chapter 13 from its first verse to its last.
+type Reference struct {
+	Book         string `json:"book"`
+	StartChapter int    `json:"start_chapter"`
+	StartVerse   int    `json:"start_verse,omitempty"`
+	EndChapter   int    `json:"end_chapter"`
+	EndVerse     int    `json:"end_verse,omitempty"`
+}
+
+// Contains reports whether the verse at chapter:verse is part of the passage.
+func (r Reference) Contains(chapter int, verse int) bool {
+	if chapter < r.StartChapter || chapter > r.EndChapter {
+		return false
+	}
+	if chapter == r.StartChapter && r.StartVerse > 0 && verse < r.StartVerse {
+		return false
+	}
+	if chapter == r.EndChapter && r.EndVerse > 0 && verse > r.EndVerse {
+		return false
+	}
+	return true
+}
+
+func (r Reference) String() string {
+	name := r.Book
+	if book, ok := bible.LookupBook(r.Book); ok {
+		name = book.Name
+	}
+	start := position(r.StartChapter, r.StartVerse)
+	switch {
+	case r.StartChapter == r.EndChapter && r.StartVerse == r.EndVerse:
+		return fmt.Sprintf("%s %s", name, start)
+	case r.StartChapter == r.EndChapter && r.StartVerse > 0 && r.EndVerse > 0:
+		return fmt.Sprintf("%s %s-%d", name, start, r.EndVerse)
+	}
+	return fmt.Sprintf("%s %s-%s", name, start, position(r.EndChapter, r.EndVerse))
+}
+
+func position(chapter int, verse int) string {
+	if verse == 0 {
+		return strconv.Itoa(chapter)
+	}
+	return fmt.Sprintf("%d:%d", chapter, verse)
+}
+
+const spec = `\d+(?:\s*:\s*\d+)?(?:\s*[-–—]\s*\d+(?:\s*:\s*\d+)?)?`
+
+var itemPattern = regexp.MustCompile(`(?i)^(?:((?:[1-3]|i{1,3}|first|second|third)?\s*[a-z][a-z .()]*?)\s*)?(` + spec + `)$`)
+
+var specPattern = regexp.MustCompile(`^(\d+)(?:\s*:\s*(\d+))?(?:\s*[-–—]\s*(\d+)(?:\s*:\s*(\d+))?)?$`)
+
+// Parse reads a list of references separated by commas or semicolons. Items
+// without a book continue the previous one: after a comma a bare number is a
+// verse when the previous item named verses, and after a semicolon it is a
+// chapter, so "John 3:16, 18; 4" is John 3:16, John 3:18 and John 4.
+func Parse(text string) ([]Reference, error) {
+	references := make([]Reference, 0)
+	book := ""
+	chapter := 0
+	verses := false
+	separator := ';'
+
+	for text = strings.TrimSpace(text); text != ""; {
+		end := strings.IndexAny(text, ",;")
+		item := text
+		next := rune(0)
+		if end >= 0 {
+			item, next = text[:end], rune(text[end])
+			text = strings.TrimSpace(text[end+1:])
+		} else {
+			text = ""
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			separator = next
+			continue
+		}
+
+		match := itemPattern.FindStringSubmatch(item)
+		if match == nil {
+			return nil, fmt.Errorf("%q: %w", item, ErrInvalidReference)
+		}
+		if name := strings.TrimSpace(match[1]); name != "" {
+			info, ok := bible.ResolveBook(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown book %q: %w", name, ErrInvalidReference)
+			}
+			book, chapter, verses, separator = info.ID, 0, false, ';'
+		}
+		if book == "" {
+			return nil, fmt.Errorf("%q has no book: %w", item, ErrInvalidReference)
+		}
+
+		reference, err := parseSpec(book, match[2], separator == ',' && verses, chapter)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", item, err)
+		}
+		references = append(references, reference)
+		chapter = reference.EndChapter
+		verses = reference.StartVerse > 0 || reference.EndVerse > 0
+		separator = next
+	}
+	if len(references) == 0 {
+		return nil, ErrInvalidReference
+	}
+	return references, nil
+}
+
+// parseSpec reads the chapter and verse part of a reference. When inChapter
+// is set a bare number is a verse of chapter rather than a chapter.
+func parseSpec(book string, text string, inChapter bool, chapter int) (Reference, error) {
+	match := specPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return Reference{}, ErrInvalidReference
+	}
+	numbers := make([]int, 4)
+	for i, group := range match[1:] {
+		if group != "" {
+			numbers[i], _ = strconv.Atoi(group)
+		}
+	}
+	start, startVerse, end, endVerse := numbers[0], numbers[1], numbers[2], numbers[3]
+	hasVerse, hasEnd := match[2] != "", match[3] != ""
+
+	reference := Reference{Book: book}
+	switch {
+	case !hasVerse && (inChapter || singleChapter(book)):
+		// 18 or 18-20 are verses of the current chapter
+		if chapter == 0 {
+			chapter = 1
+		}
+		reference.StartChapter, reference.StartVerse = chapter, start
+		reference.EndChapter, reference.EndVerse = chapter, start
+		if hasEnd {
+			if endVerse > 0 {
+				reference.EndChapter, reference.EndVerse = end, endVerse
+			} else {
+				reference.EndVerse = end
+			}
+		}
+	case !hasVerse:
+		// 13, 13-14 or 13-14:2
+		reference.StartChapter, reference.EndChapter = start, start
+		if hasEnd {
+			reference.EndChapter, reference.EndVerse = end, endVerse
+		}
+	default:
+		// 3:16, 3:16-18 or 1:1-2:3
+		reference.StartChapter, reference.StartVerse = start, startVerse
+		reference.EndChapter, reference.EndVerse = start, startVerse
+		if hasEnd && endVerse > 0 {
+			reference.EndChapter, reference.EndVerse = end, endVerse
+		} else if hasEnd {
+			reference.EndVerse = end
+		}
+	}
+	if reference.StartChapter <= 0 || reference.EndChapter < reference.StartChapter ||
+		(reference.StartChapter == reference.EndChapter && reference.EndVerse > 0 && reference.EndVerse < reference.StartVerse) {
+		return Reference{}, ErrInvalidReference
+	}
+	return reference, nil
+}
+
+var findPattern = regexp.MustCompile(`(?i)\b((?:(?:[1-3]|i{1,3}|first|second|third)\s*)?[a-z]+\.?(?:\s+of\s+[a-z]+)?)\s*(` + spec + `(?:\s*[,;]\s*` + spec + `)*)`)
+
+// Find returns the references cited in free text such as a question. To avoid
+// reading ordinary words as books, a match needs a capitalized book name, and
+// a chapter:verse, a name of at least four letters or an abbreviation ending
+// in a period, as in "Ps. 23". Chapters and verses that no versification
+// numbers are not references.
+func Find(text string) []Reference {
+	references := make([]Reference, 0)
+	for offset := 0; offset < len(text); {
+		match := findPattern.FindStringSubmatchIndex(text[offset:])
+		if match == nil {
+			break
+		}
+		name, specs := text[offset+match[2]:offset+match[3]], text[offset+match[4]:offset+match[5]]
+		found, ok := findReferences(name, specs)
+		if !ok {
+			// A rejected match may have swallowed the start of a real one,
+			// as "in 1" does in "in 1 John 4:8", so retry from the next word.
+			next := strings.IndexAny(text[offset+match[0]:], " \t\n")
+			if next < 0 {
+				break
+			}
+			offset += match[0] + next + 1
+			continue
+		}
+		references = append(references, found...)
+		offset += match[1]
+	}
+	return references
+}
+
+func findReferences(name string, specs string) ([]Reference, bool) {
+	if !capitalized(name) {
+		return nil, false
+	}
+	if !strings.Contains(specs, ":") && !strings.HasSuffix(name, ".") && len(normalize(name)) < 4 {
+		return nil, false
+	}
+	if _, ok := bible.ResolveBook(name); !ok {
+		return nil, false
+	}
+	found, err := Parse(name + " " + specs)
+	if err != nil {
+		return nil, false
+	}
+	for _, reference := range found {
+		if !numbered(reference) {
+			return nil, false
+		}
+	}
+	return found, true
+}
+
+// capitalized reports whether the book name, after any ordinal such as "1"
+// or "First", starts with a capital letter as it does when cited in prose.
+func capitalized(name string) bool {
+	fields := strings.Fields(name)
+	word := fields[0]
+	if len(fields) > 1 && ordinal(word) {
+		word = fields[1]
+	}
+	word = strings.TrimLeft(word, "123")
+	return word != "" && unicode.IsUpper(rune(word[0]))
+}
+
+func ordinal(word string) bool {
+	switch strings.ToLower(word) {
+	case "1", "2", "3", "i", "ii", "iii", "first", "second", "third":
+		return true
+	}
+	return false
+}
+
+// numbered reports whether the first and last verses of reference exist in
+// one of the known versifications.
+func numbered(reference Reference) bool {
+	for _, name := range bible.Versifications() {
+		v, _ := bible.LookupVersification(name)
+		if v.Contains(reference.Book, reference.StartChapter, max(reference.StartVerse, 1)) &&
+			v.Contains(reference.Book, reference.EndChapter, max(reference.EndVerse, 1)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(name, ".", "")), ""))
+}
+
+// singleChapter books are cited by verse alone, so "Jude 3" is Jude 1:3.
+func singleChapter(book string) bool {
+	return bible.KJV.Chapters(book) == 1
+}
@@ -0,0 +1,84 @@
+package scripture
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []Reference
+	}{
+		{"John 3:16", []Reference{{Book: "JHN", StartChapter: 3, StartVerse: 16, EndChapter: 3, EndVerse: 16}}},
+		{"Jn 3:16-18", []Reference{{Book: "JHN", StartChapter: 3, StartVerse: 16, EndChapter: 3, EndVerse: 18}}},
+		{"1 Cor 13", []Reference{{Book: "1CO", StartChapter: 13, EndChapter: 13}}},
+		{"I Corinthians 13-14", []Reference{{Book: "1CO", StartChapter: 13, EndChapter: 14}}},
+		{"Gen 1:1-2:3", []Reference{{Book: "GEN", StartChapter: 1, StartVerse: 1, EndChapter: 2, EndVerse: 3}}},
+		{"Jude 3", []Reference{{Book: "JUD", StartChapter: 1, StartVerse: 3, EndChapter: 1, EndVerse: 3}}},
+		{"Song of Solomon 2:1", []Reference{{Book: "SNG", StartChapter: 2, StartVerse: 1, EndChapter: 2, EndVerse: 1}}},
+		{"John 3:16, 18-20; 4", []Reference{
+			{Book: "JHN", StartChapter: 3, StartVerse: 16, EndChapter: 3, EndVerse: 16},
+			{Book: "JHN", StartChapter: 3, StartVerse: 18, EndChapter: 3, EndVerse: 20},
+			{Book: "JHN", StartChapter: 4, EndChapter: 4},
+		}},
+		{"Ps 23, 24; Rom 8:28", []Reference{
+			{Book: "PSA", StartChapter: 23, EndChapter: 23},
+			{Book: "PSA", StartChapter: 24, EndChapter: 24},
+			{Book: "ROM", StartChapter: 8, StartVerse: 28, EndChapter: 8, EndVerse: 28},
+		}},
+	}
+	for _, test := range tests {
+		references, err := Parse(test.text)
+		assert.NoError(t, err, test.text)
+		assert.Equal(t, test.expected, references, test.text)
+	}
+
+	for _, text := range []string{"", "Hezekiah 3:1", "3:16", "John 3:18-16", "John three"} {
+		_, err := Parse(text)
+		assert.ErrorIs(t, err, ErrInvalidReference, text)
+	}
+}
+
+func TestReference_String(t *testing.T) {
+	references, err := Parse("Jn 3:16; 3:16-18; Gen 1:1-2:3; 1 Cor 13; Ps 1-2")
+	assert.NoError(t, err)
+	strings := make([]string, len(references))
+	for i, reference := range references {
+		strings[i] = reference.String()
+	}
+	assert.Equal(t, []string{"John 3:16", "John 3:16-18", "Genesis 1:1-2:3", "1 Corinthians 13", "Psalms 1-2"}, strings)
+}
+
+func TestReference_Contains(t *testing.T) {
+	reference := Reference{Book: "GEN", StartChapter: 1, StartVerse: 3, EndChapter: 2, EndVerse: 3}
+	assert.False(t, reference.Contains(1, 2))
+	assert.True(t, reference.Contains(1, 31))
+	assert.True(t, reference.Contains(2, 3))
+	assert.False(t, reference.Contains(2, 4))
+}
+
+func TestFind(t *testing.T) {
+	references := Find("What does Jesus mean in John 3:16 and in 1 Corinthians 13? This is 3 questions.")
+	assert.Equal(t, []Reference{
+		{Book: "JHN", StartChapter: 3, StartVerse: 16, EndChapter: 3, EndVerse: 16},
+		{Book: "1CO", StartChapter: 13, EndChapter: 13},
+	}, references)
+	assert.Empty(t, Find("Am I loved? Is 3 enough?"))
+
+	assert.Equal(t, []Reference{
+		{Book: "PSA", StartChapter: 23, EndChapter: 23},
+		{Book: "1JN", StartChapter: 4, StartVerse: 8, EndChapter: 4, EndVerse: 8},
+	}, Find("Is Ps. 23 like First John 4:8?"))
+
+	for _, text := range []string{
+		"I want to mark 10 things",
+		"What do numbers 3 and 7 mean?",
+		"Genesis 50:100",
+		"Jude 30 has no such verse",
+		"Rev 3 is short",
+	} {
+		assert.Empty(t, Find(text), text)
+	}
+}
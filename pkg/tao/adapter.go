@@ -1,6 +1,9 @@
 package tao
 
-import "nvoke/pkg/embedding"
+import (
+	"fmt"
+	"nvoke/pkg/embedding"
+)
 
 type EmbeddingAdapter struct{}
 
@@ -15,3 +18,14 @@ func (a *EmbeddingAdapter) GetContent(chapter *Chapter) string {
 func (a *EmbeddingAdapter) StoreEmbedding(chapter *Chapter, embedding []float32) {
 	chapter.Embedding = embedding
 }
+
+func (a *EmbeddingAdapter) GetID(chapter *Chapter) string {
+	if chapter.Translation == "" {
+		return fmt.Sprintf("TAO.%d", chapter.Chapter)
+	}
+	return fmt.Sprintf("%s.TAO.%d", chapter.Translation, chapter.Chapter)
+}
+
+func (a *EmbeddingAdapter) GetEmbedding(chapter *Chapter) []float32 {
+	return chapter.Embedding
+}
@@ -1,7 +1,17 @@
 package tao
 
 type Chapter struct {
-	Chapter   int       `json:"chapter"`
+	// Translation is the translator the chapter was read from, such as LINN.
+	Translation string `json:"translation,omitempty"`
+	Chapter     int    `json:"chapter"`
+	// Text is the whole chapter on one line, and Stanzas keeps its lines
+	// grouped as they are printed.
 	Text      string    `json:"text"`
+	Stanzas   []Stanza  `json:"stanzas,omitempty"`
 	Embedding []float32 `json:"embedding,omitempty"`
 }
+
+// Stanza is a group of lines separated from the next stanza by a blank line.
+type Stanza struct {
+	Lines []string `json:"lines"`
+}
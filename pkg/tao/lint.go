@@ -0,0 +1,49 @@
+package tao
+
+import "nvoke/pkg/diagnostic"
+
+// Chapters is the number of chapters in the Tao Te Ching.
+const Chapters = 81
+
+// Lint reports duplicate and missing chapters and a chapter count other than
+// Chapters in each translation. Chapters out of sequence and without text
+// are reported by Parse.
+func (p *Parser) Lint(chapters []*Chapter, report *diagnostic.Reporter) {
+	translations := make([]string, 0)
+	byTranslation := make(map[string][]*Chapter)
+	for _, chapter := range chapters {
+		if _, ok := byTranslation[chapter.Translation]; !ok {
+			translations = append(translations, chapter.Translation)
+		}
+		byTranslation[chapter.Translation] = append(byTranslation[chapter.Translation], chapter)
+	}
+	for _, translation := range translations {
+		lintTranslation(translation, byTranslation[translation], report)
+	}
+}
+
+func lintTranslation(translation string, chapters []*Chapter, report *diagnostic.Reporter) {
+	prefix := ""
+	if translation != "" {
+		prefix = translation + " "
+	}
+	seen := make(map[int]int)
+	for _, chapter := range chapters {
+		seen[chapter.Chapter]++
+		if seen[chapter.Chapter] == 2 {
+			report.Errorf(0, 0, "%schapter %d: duplicate chapter", prefix, chapter.Chapter)
+		}
+	}
+	missing := make([]int, 0)
+	for number := 1; number <= Chapters; number++ {
+		if seen[number] == 0 {
+			missing = append(missing, number)
+		}
+	}
+	if len(missing) > 0 {
+		report.Errorf(0, 0, "%smissing chapters %s", prefix, diagnostic.Ranges(missing))
+	}
+	if len(chapters) != Chapters {
+		report.Errorf(0, 0, "%sfound %d chapters, expected %d", prefix, len(chapters), Chapters)
+	}
+}
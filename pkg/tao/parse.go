@@ -3,51 +3,248 @@ package tao
 import (
 	"bufio"
 	"fmt"
-	"log"
+	"io"
+	"nvoke/pkg/diagnostic"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-type Parser struct{}
+// DefaultLocation holds one directory per translation, named after the
+// translator, such as texts/tao/linn.
+const DefaultLocation = "texts/tao/"
 
-func (p *Parser) Parse(location string) []*Chapter {
-	return LoadChaptersFromFile()
+// SourceFile is the file read from each translation directory.
+const SourceFile = "tao.txt"
+
+// RawSourceFile is also read from each translation directory. Beside a
+// SourceFile it is a translation of its own, named after the directory with
+// RawTranslationSuffix, such as LINN-RAW; alone it is the directory's only
+// translation.
+const RawSourceFile = "raw.txt"
+
+// RawTranslationSuffix names the translation of a RawSourceFile kept beside
+// a SourceFile.
+const RawTranslationSuffix = "-RAW"
+
+type Parser struct {
+	Mode diagnostic.Mode
+	// Translation names the translation when location is a single file or
+	// translation directory. It defaults to the directory name in upper case.
+	Translation string
+	diagnostics diagnostic.List
 }
 
-func ParseChapters(f *os.File) []*Chapter {
-	scanner := bufio.NewScanner(f)
+// Parse reads the chapters at location, which may be a source file, a
+// translation directory holding SourceFile or RawSourceFile, or a directory
+// of translation directories.
+func (p *Parser) Parse(location string) ([]*Chapter, error) {
+	if location == "" {
+		location = DefaultLocation
+	}
+	report := diagnostic.NewReporter(p.Mode)
+	defer func() { p.diagnostics = report.Diagnostics }()
 
-	var err error
-	chapter := &Chapter{}
+	sources, err := sourceFiles(location)
+	if err != nil {
+		return nil, err
+	}
+	named := p.Translation != "" && singleDirectory(sources)
 	chapters := make([]*Chapter, 0)
+	for _, source := range sources {
+		translation := p.Translation
+		if !named {
+			if translation, err = translationOf(source); err != nil {
+				return nil, err
+			}
+		}
+		if companion(source) {
+			translation += RawTranslationSuffix
+		}
+		parsed, err := LoadChaptersFromFile(source, report)
+		if err != nil {
+			return nil, err
+		}
+		for _, chapter := range parsed {
+			chapter.Translation = translation
+		}
+		chapters = append(chapters, parsed...)
+	}
+	return chapters, nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		// special case where the line has continued
-		if strings.HasPrefix(line, "Chapter") {
-			chapters = append(chapters, chapter)
-			chapter = &Chapter{}
-			parts := strings.SplitN(line, " ", 2)
-			chapter.Chapter, err = strconv.Atoi(parts[1])
-			if err != nil {
-				log.Printf("Error parsing chapter number: %v", err)
-				return nil
+// ParserVersion is the version of the chapters Parser produces.
+const ParserVersion = 2
+
+func (p *Parser) Version() int {
+	return ParserVersion
+}
+
+func (p *Parser) Diagnostics() diagnostic.List {
+	return p.diagnostics
+}
+
+// translationOf names the translation of source after the directory holding
+// it, in upper case.
+func translationOf(source string) (string, error) {
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return "", err
+	}
+	translation := filepath.Base(filepath.Dir(path))
+	if translation == "." || translation == string(filepath.Separator) {
+		return "", fmt.Errorf("cannot tell the translation of %s, name it or move it into a translation directory", source)
+	}
+	return strings.ToUpper(translation), nil
+}
+
+// sourceFiles returns the source files of each translation at location in
+// translation order, as os.ReadDir sorts the translation directories.
+func sourceFiles(location string) ([]string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the tao source text: %v", err)
+	}
+	if !info.IsDir() {
+		return []string{location}, nil
+	}
+	if sources := translationSources(location); len(sources) > 0 {
+		return sources, nil
+	}
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		sources = append(sources, translationSources(filepath.Join(location, entry.Name()))...)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no %s or %s found in %s", SourceFile, RawSourceFile, location)
+	}
+	return sources, nil
+}
+
+// translationSources returns the SourceFile and RawSourceFile of the
+// translation directory that exist.
+func translationSources(directory string) []string {
+	sources := make([]string, 0, 2)
+	for _, name := range []string{SourceFile, RawSourceFile} {
+		source := filepath.Join(directory, name)
+		if isFile(source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// companion reports whether source is a RawSourceFile kept beside a
+// SourceFile.
+func companion(source string) bool {
+	return filepath.Base(source) == RawSourceFile && isFile(filepath.Join(filepath.Dir(source), SourceFile))
+}
+
+func singleDirectory(sources []string) bool {
+	for _, source := range sources {
+		if filepath.Dir(source) != filepath.Dir(sources[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// ParseChapters reads chapters introduced by "Chapter <n>" header lines.
+// Blank lines separate the stanzas of a chapter. Text before the first header
+// is reported and dropped, as are chapters whose header has no valid number.
+func ParseChapters(r io.Reader, report *diagnostic.Reporter) ([]*Chapter, error) {
+	scanner := bufio.NewScanner(r)
+
+	var chapter *Chapter
+	var stanza *Stanza
+	chapters := make([]*Chapter, 0)
+	preamble := 0
+	skipping := false
+
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "Chapter") {
+			chapter = nil
+			stanza = nil
+			skipping = false
+			parts := strings.Fields(text)
+			if len(parts) != 2 {
+				skipping = true
+				if err := report.Errorf(line, 1, "chapter header %q should be \"Chapter <number>\"", text); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			column := strings.Index(text, parts[1]) + 1
+			number, err := strconv.Atoi(parts[1])
+			if err != nil || number <= 0 {
+				skipping = true
+				if err := report.Errorf(line, column, "invalid chapter number %q", parts[1]); err != nil {
+					return nil, err
+				}
+				continue
 			}
-		} else {
-			chapter.Text = fmt.Sprintf("%s %s", chapter.Text, strings.TrimSpace(line))
+			if len(chapters) > 0 && number != chapters[len(chapters)-1].Chapter+1 {
+				report.Warnf(line, column, "chapter %d follows chapter %d", number, chapters[len(chapters)-1].Chapter)
+			}
+			chapter = &Chapter{Chapter: number}
+			chapters = append(chapters, chapter)
+			continue
 		}
 
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case skipping:
+		case trimmed == "":
+			stanza = nil
+		case chapter == nil:
+			if preamble == 0 {
+				report.Warnf(line, 1, "text before the first chapter header is ignored")
+			}
+			preamble++
+		default:
+			if chapter.Text == "" {
+				chapter.Text = trimmed
+			} else {
+				chapter.Text = fmt.Sprintf("%s %s", chapter.Text, trimmed)
+			}
+			if stanza == nil {
+				chapter.Stanzas = append(chapter.Stanzas, Stanza{})
+				stanza = &chapter.Stanzas[len(chapter.Stanzas)-1]
+			}
+			stanza.Lines = append(stanza.Lines, trimmed)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, chapter := range chapters {
+		if chapter.Text == "" {
+			report.Warnf(0, 0, "chapter %d has no text", chapter.Chapter)
+		}
 	}
-	return chapters[1:]
+	return chapters, nil
 }
 
-func LoadChaptersFromFile() []*Chapter {
-	f, err := os.Open("texts/tao/linn/tao.txt")
+func LoadChaptersFromFile(path string, report *diagnostic.Reporter) ([]*Chapter, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		fmt.Printf("Failed to read the tao source text: %v\n", err)
-		return nil
+		return nil, fmt.Errorf("failed to read the tao source text: %v", err)
 	}
-	chapters := ParseChapters(f)
-	return chapters
+	defer f.Close()
+	report.File = path
+	return ParseChapters(f, report)
 }
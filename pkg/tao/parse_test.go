@@ -0,0 +1,133 @@
+package tao
+
+import (
+	"nvoke/pkg/diagnostic"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const source = `Tao Te Ching
+translated by William Scott Wilson
+
+Chapter 1
+The tao that can be told
+is not the eternal Tao.
+
+Chapter x
+Chapter 2
+When people see some things as beautiful,
+other things become ugly.
+
+When people see some things as good,
+other things become bad.
+`
+
+func TestParseChapters(t *testing.T) {
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	chapters, err := ParseChapters(strings.NewReader(source), report)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Chapter{
+		{
+			Chapter: 1,
+			Text:    "The tao that can be told is not the eternal Tao.",
+			Stanzas: []Stanza{{Lines: []string{"The tao that can be told", "is not the eternal Tao."}}},
+		},
+		{
+			Chapter: 2,
+			Text:    "When people see some things as beautiful, other things become ugly. When people see some things as good, other things become bad.",
+			Stanzas: []Stanza{
+				{Lines: []string{"When people see some things as beautiful,", "other things become ugly."}},
+				{Lines: []string{"When people see some things as good,", "other things become bad."}},
+			},
+		},
+	}, chapters)
+	assert.Len(t, report.Diagnostics.Warnings(), 1)
+	assert.Len(t, report.Diagnostics.Errors(), 1)
+	assert.Equal(t, 8, report.Diagnostics.Errors()[0].Line)
+
+	_, err = ParseChapters(strings.NewReader(source), diagnostic.NewReporter(diagnostic.Strict))
+	assert.Error(t, err)
+}
+
+func TestParser_Lint(t *testing.T) {
+	chapters, err := ParseChapters(strings.NewReader(source), diagnostic.NewReporter(diagnostic.Lenient))
+	assert.NoError(t, err)
+	chapters = append(chapters, &Chapter{Chapter: 2, Text: "Again."})
+
+	report := diagnostic.NewReporter(diagnostic.Lenient)
+	(&Parser{}).Lint(chapters, report)
+	messages := make([]string, 0)
+	for _, d := range report.Diagnostics {
+		messages = append(messages, d.Message)
+	}
+	assert.Equal(t, []string{
+		"chapter 2: duplicate chapter",
+		"missing chapters 3-81",
+		"found 3 chapters, expected 81",
+	}, messages)
+}
+
+func TestParser_Translations(t *testing.T) {
+	root := t.TempDir()
+	for _, translation := range []string{"linn", "mitchell"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(root, translation), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(root, translation, SourceFile), []byte("Chapter 1\nThe tao that can be told\n"), 0644))
+	}
+	// raw.txt beside a tao.txt is a translation of its own.
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "linn", RawSourceFile), []byte("Chapter 1\nThe way that can be walked\n"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "wilson"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "wilson", RawSourceFile), []byte("Chapter 1\nThe tao that can be spoken\n"), 0644))
+
+	chapters, err := (&Parser{}).Parse(root)
+	assert.NoError(t, err)
+	if assert.Len(t, chapters, 4) {
+		assert.Equal(t, "LINN", chapters[0].Translation)
+		assert.Equal(t, "The tao that can be told", chapters[0].Text)
+		assert.Equal(t, "LINN-RAW", chapters[1].Translation)
+		assert.Equal(t, "The way that can be walked", chapters[1].Text)
+		assert.Equal(t, "MITCHELL", chapters[2].Translation)
+		assert.Equal(t, "WILSON", chapters[3].Translation)
+		assert.Equal(t, "The tao that can be spoken", chapters[3].Text)
+	}
+
+	chapters, err = (&Parser{Translation: "FEN"}).Parse(filepath.Join(root, "linn"))
+	assert.NoError(t, err)
+	if assert.Len(t, chapters, 2) {
+		assert.Equal(t, "FEN", chapters[0].Translation)
+		assert.Equal(t, "FEN-RAW", chapters[1].Translation)
+	}
+
+	chapters, err = (&Parser{}).Parse(filepath.Join(root, "wilson"))
+	assert.NoError(t, err)
+	if assert.Len(t, chapters, 1) {
+		assert.Equal(t, "WILSON", chapters[0].Translation)
+	}
+
+	chapters, err = (&Parser{Translation: "FEN"}).Parse(filepath.Join(root, "linn", SourceFile))
+	assert.NoError(t, err)
+	if assert.Len(t, chapters, 1) {
+		assert.Equal(t, "FEN", chapters[0].Translation)
+	}
+}
+
+func TestParser_BareFile(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, SourceFile), []byte("Chapter 1\nThe tao that can be told\n"), 0644))
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(root))
+	defer os.Chdir(wd)
+
+	// A file given without a directory is named after the working directory.
+	chapters, err := (&Parser{}).Parse(SourceFile)
+	assert.NoError(t, err)
+	if assert.Len(t, chapters, 1) {
+		assert.Equal(t, strings.ToUpper(filepath.Base(root)), chapters[0].Translation)
+	}
+	_, err = translationOf("/" + SourceFile)
+	assert.Error(t, err)
+}
@@ -8,14 +8,36 @@ import (
 type Persona struct{}
 
 func (t *Persona) BuildCompletionContext(ctx context.Context, items []interface{}) (string, error) {
-	contextString := "Using the following chapters for context to answer the question. Do not use other information or sources. \n context: "
+	contextString := "Using the following chapters for context to answer the question. Do not use other information or sources. "
+	if translations(items) > 1 {
+		contextString += "Chapters given in several translations should be compared, noting where their renderings differ. "
+	}
+	contextString += "\n context: "
 	for _, val := range items {
-		chapter := val.(Chapter)
-		contextString += fmt.Sprintf("Chapter %v -- %v ", chapter.Chapter, chapter.Text)
+		chapter, ok := val.(*Chapter)
+		if !ok {
+			return "", fmt.Errorf("unexpected document type %T", val)
+		}
+		if chapter.Translation == "" {
+			contextString += fmt.Sprintf("Chapter %v -- %v ", chapter.Chapter, chapter.Text)
+		} else {
+			contextString += fmt.Sprintf("Chapter %v (%v) -- %v ", chapter.Chapter, chapter.Translation, chapter.Text)
+		}
 	}
 	return contextString, nil
 }
 
+// translations counts the distinct translations of the chapters in items.
+func translations(items []interface{}) int {
+	seen := make(map[string]bool)
+	for _, val := range items {
+		if chapter, ok := val.(*Chapter); ok {
+			seen[chapter.Translation] = true
+		}
+	}
+	return len(seen)
+}
+
 func (t *Persona) Prompt() string {
 	return "You are Lao Tzu. You will respond in language like that of the Tao Te Ching as if you are Lao Tzu talking to a disciple."
 }
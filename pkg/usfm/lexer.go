@@ -0,0 +1,190 @@
+// Package usfm tokenizes Unified Standard Format Markers documents.
+package usfm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind identifies the type of a Token.
+type Kind int
+
+const (
+	// Text is content between markers.
+	Text Kind = iota
+	// Marker opens a paragraph, character or note marker such as \p or \w.
+	Marker
+	// EndMarker closes a character or note marker such as \w*. Milestones are
+	// closed with \* which produces an EndMarker with an empty Name.
+	EndMarker
+	// Attributes is the |key="value" list that precedes an end marker.
+	Attributes
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Text:
+		return "text"
+	case Marker:
+		return "marker"
+	case EndMarker:
+		return "end marker"
+	case Attributes:
+		return "attributes"
+	}
+	return "unknown"
+}
+
+// Token is a lexical element of a USFM document. Line and Column are one based
+// and point at the first character of the token.
+type Token struct {
+	Kind   Kind
+	Name   string
+	Text   string
+	Nested bool
+	Line   int
+	Column int
+}
+
+// Lexer splits USFM source into tokens.
+type Lexer struct {
+	source string
+	offset int
+	line   int
+	column int
+}
+
+func NewLexer(source string) *Lexer {
+	return &Lexer{source: source, line: 1, column: 1}
+}
+
+// Tokenize returns every token in source.
+func Tokenize(source string) []Token {
+	lexer := NewLexer(source)
+	tokens := make([]Token, 0)
+	for {
+		token, ok := lexer.Next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// Next returns the next token, or false at the end of the input.
+func (l *Lexer) Next() (Token, bool) {
+	if l.offset >= len(l.source) {
+		return Token{}, false
+	}
+	token := Token{Line: l.line, Column: l.column}
+	switch l.source[l.offset] {
+	case '\\':
+		l.advance(1)
+		if strings.HasPrefix(l.source[l.offset:], "+") {
+			token.Nested = true
+			l.advance(1)
+		}
+		start := l.offset
+		for l.offset < len(l.source) && isMarkerChar(l.source[l.offset]) {
+			l.advance(1)
+		}
+		token.Name = l.source[start:l.offset]
+		if l.offset < len(l.source) && l.source[l.offset] == '*' {
+			l.advance(1)
+			token.Kind = EndMarker
+			return token, true
+		}
+		token.Kind = Marker
+		// A single whitespace character separates a marker from its content.
+		if r, size := utf8.DecodeRuneInString(l.source[l.offset:]); size > 0 && unicode.IsSpace(r) {
+			if r == '\r' && strings.HasPrefix(l.source[l.offset:], "\r\n") {
+				size = 2
+			}
+			l.advance(size)
+		}
+		return token, true
+	case '|':
+		l.advance(1)
+		token.Kind = Attributes
+		token.Text = l.until("\\")
+		return token, true
+	}
+	token.Kind = Text
+	token.Text = l.until("\\|")
+	return token, true
+}
+
+func (l *Lexer) until(stop string) string {
+	start := l.offset
+	for l.offset < len(l.source) && !strings.ContainsRune(stop, rune(l.source[l.offset])) {
+		l.advance(1)
+	}
+	return l.source[start:l.offset]
+}
+
+func (l *Lexer) advance(n int) {
+	for i := 0; i < n; i++ {
+		if l.source[l.offset] == '\n' {
+			l.line++
+			l.column = 1
+		} else if l.source[l.offset]&0xC0 != 0x80 {
+			// Count columns in runes rather than bytes.
+			l.column++
+		}
+		l.offset++
+	}
+}
+
+func isMarkerChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// Split separates a trailing level number from a marker name, so q2 becomes
+// ("q", 2). Markers without a number have level 1.
+func Split(name string) (string, int) {
+	end := len(name)
+	for end > 0 && name[end-1] >= '0' && name[end-1] <= '9' {
+		end--
+	}
+	if end == len(name) || end == 0 {
+		return name, 1
+	}
+	level := 0
+	for _, c := range name[end:] {
+		level = level*10 + int(c-'0')
+	}
+	return name[:end], level
+}
+
+// ParseAttributes parses the text of an Attributes token. A value without a
+// key, as in \w gracious|grace\w*, is returned under the "default" key.
+func ParseAttributes(text string) map[string]string {
+	attributes := make(map[string]string)
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, "=") {
+		if text != "" {
+			attributes["default"] = text
+		}
+		return attributes
+	}
+	for text != "" {
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(text[:eq])
+		text = strings.TrimLeft(text[eq+1:], " ")
+		if !strings.HasPrefix(text, "\"") {
+			break
+		}
+		end := strings.IndexByte(text[1:], '"')
+		if end < 0 {
+			attributes[key] = text[1:]
+			break
+		}
+		attributes[key] = text[1 : end+1]
+		text = strings.TrimSpace(text[end+2:])
+	}
+	return attributes
+}
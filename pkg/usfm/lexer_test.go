@@ -0,0 +1,38 @@
+package usfm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("\\v 1 \\w In|strong=\"H7225\"\\w* the\n\\+nd Lord\\+nd*")
+	assert.Equal(t, []Token{
+		{Kind: Marker, Name: "v", Line: 1, Column: 1},
+		{Kind: Text, Text: "1 ", Line: 1, Column: 4},
+		{Kind: Marker, Name: "w", Line: 1, Column: 6},
+		{Kind: Text, Text: "In", Line: 1, Column: 9},
+		{Kind: Attributes, Text: "strong=\"H7225\"", Line: 1, Column: 11},
+		{Kind: EndMarker, Name: "w", Line: 1, Column: 26},
+		{Kind: Text, Text: " the\n", Line: 1, Column: 29},
+		{Kind: Marker, Name: "nd", Nested: true, Line: 2, Column: 1},
+		{Kind: Text, Text: "Lord", Line: 2, Column: 6},
+		{Kind: EndMarker, Name: "nd", Nested: true, Line: 2, Column: 10},
+	}, tokens)
+}
+
+func TestSplit(t *testing.T) {
+	name, level := Split("q2")
+	assert.Equal(t, "q", name)
+	assert.Equal(t, 2, level)
+
+	name, level = Split("p")
+	assert.Equal(t, "p", name)
+	assert.Equal(t, 1, level)
+}
+
+func TestParseAttributes(t *testing.T) {
+	assert.Equal(t, map[string]string{"lemma": "grace", "strong": "G5485"}, ParseAttributes(`lemma="grace" strong="G5485"`))
+	assert.Equal(t, map[string]string{"default": "grace"}, ParseAttributes("grace"))
+}